@@ -0,0 +1,79 @@
+//Package apicheck explains the opaque "could not find the requested resource" error
+//returned when a whole group/version is missing from the cluster
+package apicheck
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+//Unavailable returns err unchanged unless it is a 404 for the resource type itself
+//and discovery confirms the group/version is not served, in which case the
+//available group/versions are listed. Discovery is only asked for that error class.
+func Unavailable(client discovery.DiscoveryInterface, gv schema.GroupVersion, err error) error {
+	if !missingResource(err) {
+		return err
+	}
+
+	groups, discoveryErr := client.ServerGroups()
+	if discoveryErr != nil {
+		return err
+	}
+
+	var available []string
+	for _, group := range groups.Groups {
+		for _, version := range group.Versions {
+			if version.GroupVersion == gv.String() {
+				return err
+			}
+			available = append(available, version.GroupVersion)
+		}
+	}
+
+	return &UnavailableError{GroupVersion: gv, Available: available, Err: err}
+}
+
+//UnavailableError is returned by Unavailable when the cluster does not serve the group/version at all
+//It wraps the original 404, so apierrors.IsNotFound still holds for it
+type UnavailableError struct {
+	GroupVersion schema.GroupVersion
+	//Group/versions the server reported instead
+	Available []string
+	Err       error
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("the %s API is not available on this cluster (server reports only: %s): %v", e.GroupVersion, strings.Join(e.Available, ", "), e.Err)
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
+
+//IsUnavailable reports whether err is, or wraps, an UnavailableError
+//Unlike a missing object it holds for every namespace, so callers should not treat it per object
+func IsUnavailable(err error) bool {
+	var unavailable *UnavailableError
+	return errors.As(err, &unavailable)
+}
+
+//A missing object is reported as `<resource> "<name>" not found`, while a missing resource type
+//gets the generic message client-go uses for 404 responses that are not a Status object
+func missingResource(err error) bool {
+	if !apierrors.IsNotFound(err) {
+		return false
+	}
+	var statusErr *apierrors.StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	status := statusErr.ErrStatus
+	return status.Details == nil ||
+		strings.Contains(status.Message, "the server could not find the requested resource") ||
+		strings.Contains(status.Message, "404 page not found")
+}
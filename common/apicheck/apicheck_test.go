@@ -0,0 +1,115 @@
+package apicheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var (
+	appsV1      = schema.GroupVersion{Group: "apps", Version: "v1"}
+	deployments = schema.GroupResource{Group: "apps", Resource: "deployments"}
+)
+
+//Function to return a clientset whose deployment gets fail with getErr and whose discovery serves groupVersions
+func newClientset(getErr error, groupVersions ...string) *fake.Clientset {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("get", "deployments", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, getErr
+	})
+	discovery := clientset.Discovery().(*fakediscovery.FakeDiscovery)
+	for _, groupVersion := range groupVersions {
+		discovery.Resources = append(discovery.Resources, &metav1.APIResourceList{GroupVersion: groupVersion})
+	}
+	return clientset
+}
+
+//Function to get deployment web through the clientset and pass the error to Unavailable
+func getDeployment(clientset *fake.Clientset) error {
+	_, err := clientset.AppsV1().Deployments("team").Get(context.TODO(), "web", metav1.GetOptions{})
+	return Unavailable(clientset.Discovery(), appsV1, err)
+}
+
+func discoveryCalls(clientset *fake.Clientset) int {
+	calls := 0
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() == "get" && action.GetResource().Resource == "group" {
+			calls++
+		}
+	}
+	return calls
+}
+
+func TestUnavailableGroupAbsent(t *testing.T) {
+	notServed := apierrors.NewGenericServerResponse(404, "get", deployments, "web", "", 0, true)
+	clientset := newClientset(notServed, "v1")
+
+	err := getDeployment(clientset)
+	want := "the apps/v1 API is not available on this cluster (server reports only: v1): " + notServed.Error()
+	if err == nil || err.Error() != want {
+		t.Fatalf("Unavailable = %v, want %q", err, want)
+	}
+	//Callers still recognize the error
+	if !errors.Is(err, notServed) || !apierrors.IsNotFound(err) {
+		t.Errorf("rewritten error does not wrap the original")
+	}
+	if !IsUnavailable(err) || !IsUnavailable(fmt.Errorf("namespace team: %w", err)) {
+		t.Errorf("IsUnavailable(%v) = false, want true also when wrapped", err)
+	}
+	if calls := discoveryCalls(clientset); calls != 1 {
+		t.Errorf("got %d discovery calls, want 1", calls)
+	}
+}
+
+func TestUnavailableObjectNotFound(t *testing.T) {
+	objectNotFound := apierrors.NewNotFound(deployments, "web")
+	clientset := newClientset(objectNotFound, "v1")
+
+	err := getDeployment(clientset)
+	if err != objectNotFound {
+		t.Errorf("Unavailable = %v, want the NotFound unchanged", err)
+	}
+	if IsUnavailable(err) {
+		t.Errorf("IsUnavailable(%v) = true for a missing object", err)
+	}
+	if calls := discoveryCalls(clientset); calls != 0 {
+		t.Errorf("got %d discovery calls for a missing object, want none", calls)
+	}
+}
+
+func TestUnavailableGroupPresent(t *testing.T) {
+	notServed := apierrors.NewGenericServerResponse(404, "get", deployments, "web", "", 0, true)
+	clientset := newClientset(notServed, "v1", "apps/v1")
+
+	if err := getDeployment(clientset); err != notServed {
+		t.Errorf("Unavailable = %v, want the error unchanged while apps/v1 is served", err)
+	}
+	if calls := discoveryCalls(clientset); calls != 1 {
+		t.Errorf("got %d discovery calls, want 1", calls)
+	}
+}
+
+func TestUnavailableOtherErrors(t *testing.T) {
+	for _, err := range []error{
+		nil,
+		apierrors.NewForbidden(deployments, "web", errors.New("denied")),
+		apierrors.NewConflict(deployments, "web", errors.New("modified")),
+	} {
+		clientset := newClientset(err, "v1")
+		if got := getDeployment(clientset); got != err {
+			t.Errorf("Unavailable(%v) = %v, want it unchanged", err, got)
+		}
+		if calls := discoveryCalls(clientset); calls != 0 {
+			t.Errorf("Unavailable(%v) made %d discovery calls, want none", err, calls)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+//Package apiserver picks the API server endpoint for HA control planes reachable through several addresses
+//The endpoints given with --apiserver are tried in order and the first one answering is used for the whole command
+package apiserver
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"common/vlog"
+
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+)
+
+//How long a single endpoint gets to answer before the next one is tried
+const probeTimeout = 5 * time.Second
+
+//AddFlags registers the repeatable --apiserver flag
+func AddFlags(flags *pflag.FlagSet, endpoints *[]string) {
+	flags.StringArrayVar(endpoints, "apiserver", nil, "API server endpoint to try in order until one responds, can be repeated, e.g. https://10.0.0.1:6443")
+}
+
+//Resolve applies --apiserver to config, server is the value of --server which cannot be combined with it
+//Without endpoints config is returned unchanged
+func Resolve(config *rest.Config, server string, endpoints []string, log *vlog.Logger) (*rest.Config, error) {
+	if len(endpoints) == 0 {
+		return config, nil
+	}
+	if len(server) > 0 {
+		return nil, fmt.Errorf("--apiserver cannot be combined with --server")
+	}
+	return Select(config, endpoints, log)
+}
+
+//Select returns a copy of config pointing to the first endpoint that answers /version
+//Credentials and CA of config are kept, so the serving certificate must be valid for every endpoint
+func Select(config *rest.Config, endpoints []string, log *vlog.Logger) (*rest.Config, error) {
+	var failures []string
+	var lastErr error
+	for _, endpoint := range endpoints {
+		candidate := rest.CopyConfig(config)
+		candidate.Host = endpoint
+
+		start := time.Now()
+		err := probe(candidate)
+		log.V(2).Infof("GET %s/version (%v): %v", endpoint, time.Since(start), vlog.ErrOrOK(err))
+		if err == nil {
+			log.V(1).Infof("using apiserver %s", endpoint)
+			return candidate, nil
+		}
+		log.V(1).Infof("apiserver %s unreachable, trying the next one: %v", endpoint, err)
+		failures = append(failures, fmt.Sprintf("%s: %v", endpoint, err))
+		lastErr = err
+	}
+	return nil, fmt.Errorf("no --apiserver endpoint responded (%s): %w", strings.Join(failures, "; "), lastErr)
+}
+
+func probe(config *rest.Config) error {
+	probeConfig := rest.CopyConfig(config)
+	probeConfig.Timeout = probeTimeout
+	client, err := discovery.NewDiscoveryClientForConfig(probeConfig)
+	if err != nil {
+		return err
+	}
+	_, err = client.ServerVersion()
+	return err
+}
+
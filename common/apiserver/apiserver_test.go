@@ -0,0 +1,117 @@
+package apiserver
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"common/vlog"
+
+	"k8s.io/client-go/rest"
+)
+
+//Function to return a server answering /version like a v1.24 API server, it records the bearer token it got
+func newVersionServer(t *testing.T, token *string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/version" {
+			http.NotFound(w, r)
+			return
+		}
+		*token = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"major":"1","minor":"24","gitVersion":"v1.24.1"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+//Function to return an endpoint nothing listens on
+func closedEndpoint(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint := "http://" + listener.Addr().String()
+	listener.Close()
+	return endpoint
+}
+
+func TestSelectFailsOver(t *testing.T) {
+	var token string
+	server := newVersionServer(t, &token)
+	down := closedEndpoint(t)
+	config := &rest.Config{Host: "https://kubeconfig.example.com", BearerToken: "secret"}
+	var logs bytes.Buffer
+
+	selected, err := Select(config, []string{down, server.URL}, vlog.New(&logs, 2))
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	if selected.Host != server.URL {
+		t.Errorf("Host = %q, want %q", selected.Host, server.URL)
+	}
+	if selected.BearerToken != "secret" || token != "Bearer secret" {
+		t.Errorf("credentials not kept, token %q sent %q", selected.BearerToken, token)
+	}
+	if config.Host != "https://kubeconfig.example.com" {
+		t.Errorf("Select changed the given config to %q", config.Host)
+	}
+	for _, want := range []string{"apiserver " + down + " unreachable, trying the next one", "using apiserver " + server.URL} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log misses %q:\n%s", want, logs.String())
+		}
+	}
+}
+
+func TestSelectFirstResponding(t *testing.T) {
+	var first, second string
+	a := newVersionServer(t, &first)
+	b := newVersionServer(t, &second)
+
+	selected, err := Select(&rest.Config{}, []string{a.URL, b.URL}, vlog.New(&bytes.Buffer{}, 0))
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	if selected.Host != a.URL {
+		t.Errorf("Host = %q, want the first endpoint %q", selected.Host, a.URL)
+	}
+}
+
+func TestSelectNoneResponding(t *testing.T) {
+	down := []string{closedEndpoint(t), closedEndpoint(t)}
+
+	_, err := Select(&rest.Config{}, down, vlog.New(&bytes.Buffer{}, 0))
+	if err == nil {
+		t.Fatal("Select = nil, want an error")
+	}
+	message := err.Error()
+	if !strings.HasPrefix(message, "no --apiserver endpoint responded (") {
+		t.Errorf("error = %q", message)
+	}
+	for _, endpoint := range down {
+		if !strings.Contains(message, endpoint+": ") {
+			t.Errorf("error misses endpoint %s: %q", endpoint, message)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	var token string
+	server := newVersionServer(t, &token)
+	config := &rest.Config{Host: "https://kubeconfig.example.com"}
+	log := vlog.New(&bytes.Buffer{}, 0)
+
+	if got, err := Resolve(config, "", nil, log); err != nil || got != config {
+		t.Errorf("Resolve without endpoints = %v, %v, want the config unchanged", got, err)
+	}
+	if _, err := Resolve(config, "https://10.0.0.9:6443", []string{server.URL}, log); err == nil || err.Error() != "--apiserver cannot be combined with --server" {
+		t.Errorf("Resolve with --server = %v, want the conflict", err)
+	}
+	got, err := Resolve(config, "", []string{closedEndpoint(t), server.URL}, log)
+	if err != nil || got.Host != server.URL {
+		t.Errorf("Resolve = %v, %v, want the second endpoint %s", got, err, server.URL)
+	}
+}
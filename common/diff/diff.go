@@ -0,0 +1,195 @@
+//Package diff produces unified line diffs, used to show how an object changes
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+//Options for Unified
+type Options struct {
+	//Names printed in the "---" and "+++" header lines
+	From string
+	To   string
+	//Unchanged lines printed around every change
+	Context int
+}
+
+type op int
+
+const (
+	equal op = iota
+	insert
+	remove
+)
+
+type line struct {
+	op   op
+	text string
+}
+
+//Unified returns the unified diff of a and b, or "" when they are equal
+func Unified(a, b string, opts Options) string {
+	lines := compare(split(a), split(b))
+
+	var out strings.Builder
+	for _, h := range hunks(lines, opts.Context) {
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", opts.From, opts.To)
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", h.fromLine, h.fromCount, h.toLine, h.toCount)
+		for _, l := range lines[h.start:h.end] {
+			switch l.op {
+			case insert:
+				out.WriteString("+" + l.text + "\n")
+			case remove:
+				out.WriteString("-" + l.text + "\n")
+			default:
+				out.WriteString(" " + l.text + "\n")
+			}
+		}
+	}
+	return out.String()
+}
+
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+//Longest common subsequence, objects are a few hundred lines so the quadratic table is fine
+func compare(a, b []string) []line {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []line
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, line{equal, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, line{remove, a[i]})
+			i++
+		default:
+			lines = append(lines, line{insert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, line{remove, a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, line{insert, b[j]})
+	}
+	return lines
+}
+
+type hunk struct {
+	start, end          int
+	fromLine, fromCount int
+	toLine, toCount     int
+}
+
+//Group the changed lines into hunks, changes closer than 2*context lines share a hunk
+func hunks(lines []line, context int) []hunk {
+	var result []hunk
+	for i := 0; i < len(lines); i++ {
+		if lines[i].op == equal {
+			continue
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		if n := len(result); n > 0 && start <= result[n-1].end {
+			start = result[n-1].start
+			result = result[:n-1]
+		}
+
+		end := i + 1
+		for end < len(lines) && lines[end].op != equal {
+			end++
+		}
+		i = end - 1
+		end += context
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		result = append(result, hunk{start: start, end: end})
+	}
+
+	for n := range result {
+		h := &result[n]
+		from, to := 1, 1
+		for _, l := range lines[:h.start] {
+			if l.op != insert {
+				from++
+			}
+			if l.op != remove {
+				to++
+			}
+		}
+		for _, l := range lines[h.start:h.end] {
+			if l.op != insert {
+				h.fromCount++
+			}
+			if l.op != remove {
+				h.toCount++
+			}
+		}
+		//An empty side names the line before the hunk, like diff -u
+		if h.fromCount == 0 {
+			from--
+		}
+		if h.toCount == 0 {
+			to--
+		}
+		h.fromLine, h.toLine = from, to
+	}
+	return result
+}
+
+const (
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorReset = "\033[0m"
+)
+
+//Colorize prints added lines green and removed lines red, for terminals only
+//Only the "---" and "+++" lines before the first hunk are file headers, in a hunk they are changed content
+func Colorize(unified string) string {
+	lines := strings.Split(unified, "\n")
+	header := true
+	for i, l := range lines {
+		switch {
+		case strings.HasPrefix(l, "@@"):
+			header = false
+		case header:
+		case strings.HasPrefix(l, "+"):
+			lines[i] = colorGreen + l + colorReset
+		case strings.HasPrefix(l, "-"):
+			lines[i] = colorRed + l + colorReset
+		}
+	}
+	return strings.Join(lines, "\n")
+}
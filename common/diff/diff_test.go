@@ -0,0 +1,123 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+//Function to join lines with a trailing newline, like yaml output
+func text(lines ...string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestUnified(t *testing.T) {
+	ten := []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}
+	replace := func(lines []string, changes map[int]string) []string {
+		result := append([]string(nil), lines...)
+		for i, l := range changes {
+			result[i] = l
+		}
+		return result
+	}
+
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    string
+	}{
+		{
+			name: "identical",
+			a:    text(ten...), b: text(ten...), context: 3,
+			want: "",
+		},
+		{
+			name:    "both empty",
+			context: 3,
+			want:    "",
+		},
+		{
+			name: "insert into empty",
+			b:    text("a", "b"), context: 3,
+			want: text("--- a", "+++ b", "@@ -0,0 +1,2 @@", "+a", "+b"),
+		},
+		{
+			name: "delete everything",
+			a:    text("a", "b"), context: 3,
+			want: text("--- a", "+++ b", "@@ -1,2 +0,0 @@", "-a", "-b"),
+		},
+		{
+			name: "pure insert",
+			a:    text("1", "2", "3", "4"), b: text("1", "2", "new", "3", "4"), context: 1,
+			want: text("--- a", "+++ b", "@@ -2,2 +2,3 @@", " 2", "+new", " 3"),
+		},
+		{
+			name: "pure insert without context",
+			a:    text("1", "2", "3", "4"), b: text("1", "2", "new", "3", "4"), context: 0,
+			want: text("--- a", "+++ b", "@@ -2,0 +3,1 @@", "+new"),
+		},
+		{
+			name: "pure delete",
+			a:    text("1", "2", "3", "4"), b: text("1", "3", "4"), context: 1,
+			want: text("--- a", "+++ b", "@@ -1,3 +1,2 @@", " 1", "-2", " 3"),
+		},
+		{
+			name: "pure delete without context",
+			a:    text("1", "2", "3", "4"), b: text("1", "3", "4"), context: 0,
+			want: text("--- a", "+++ b", "@@ -2,1 +1,0 @@", "-2"),
+		},
+		{
+			name: "change at start",
+			a:    text(ten...), b: text(replace(ten, map[int]string{0: "one"})...), context: 3,
+			want: text("--- a", "+++ b", "@@ -1,4 +1,4 @@", "-1", "+one", " 2", " 3", " 4"),
+		},
+		{
+			name: "change at end",
+			a:    text(ten...), b: text(replace(ten, map[int]string{9: "ten"})...), context: 3,
+			want: text("--- a", "+++ b", "@@ -7,4 +7,4 @@", " 7", " 8", " 9", "-10", "+ten"),
+		},
+		{
+			//Four unchanged lines between the changes, at most 2*context, one hunk
+			name: "close changes merge",
+			a:    text(ten...), b: text(replace(ten, map[int]string{2: "three", 7: "eight"})...), context: 2,
+			want: text("--- a", "+++ b", "@@ -1,10 +1,10 @@",
+				" 1", " 2", "-3", "+three", " 4", " 5", " 6", " 7", "-8", "+eight", " 9", " 10"),
+		},
+		{
+			//Six unchanged lines between the changes, more than 2*context, two hunks
+			name: "far changes stay apart",
+			a:    text(ten...), b: text(replace(ten, map[int]string{1: "two", 8: "nine"})...), context: 2,
+			want: text("--- a", "+++ b",
+				"@@ -1,4 +1,4 @@", " 1", "-2", "+two", " 3", " 4",
+				"@@ -7,4 +7,4 @@", " 7", " 8", "-9", "+nine", " 10"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Unified(tt.a, tt.b, Options{From: "a", To: "b", Context: tt.context})
+			if got != tt.want {
+				t.Errorf("Unified =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColorize(t *testing.T) {
+	//Removed "--" and added "++" content lines look like file headers
+	unified := Unified(text("a", "-- x", "b"), text("a", "++ y", "b"), Options{From: "live", To: "edited", Context: 1})
+	want := text(
+		"--- live",
+		"+++ edited",
+		"@@ -1,3 +1,3 @@",
+		" a",
+		colorRed+"--- x"+colorReset,
+		colorGreen+"+++ y"+colorReset,
+		" b",
+	)
+	if got := Colorize(unified); got != want {
+		t.Errorf("Colorize =\n%q\nwant\n%q", got, want)
+	}
+}
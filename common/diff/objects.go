@@ -0,0 +1,89 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"common/prompt"
+	"common/vlog"
+
+	"sigs.k8s.io/yaml"
+)
+
+//Objects marshals both objects to yaml and returns their unified diff
+func Objects(a, b interface{}, opts Options) (string, error) {
+	from, err := yaml.Marshal(a)
+	if err != nil {
+		return "", err
+	}
+	to, err := yaml.Marshal(b)
+	if err != nil {
+		return "", err
+	}
+	return Unified(string(from), string(to), opts), nil
+}
+
+//Changed returns the diff of an object before and after a write
+//managedFields are left out, they change on every write and would bury the real change in large specs
+func Changed(before, after interface{}, opts Options) (string, error) {
+	from, err := withoutManagedFields(before)
+	if err != nil {
+		return "", err
+	}
+	to, err := withoutManagedFields(after)
+	if err != nil {
+		return "", err
+	}
+	return Objects(from, to, opts)
+}
+
+//PrintChanged writes the Changed diff of an object named name to out, colored on a terminal
+//"No changes.." is written when the write left the object as it was
+func PrintChanged(out io.Writer, before, after interface{}, name string) error {
+	changes, err := Changed(before, after, Options{From: "before/" + name, To: "after/" + name, Context: 2})
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "No changes..")
+		return nil
+	}
+	if prompt.IsTerminal(out) {
+		changes = Colorize(changes)
+	}
+	fmt.Fprint(out, changes)
+	return nil
+}
+
+//Log writes how the object sent differs from the one the server returned, at level 3 of log
+func Log(log *vlog.Logger, sent, received interface{}) {
+	if !log.V(3).Enabled() {
+		return
+	}
+	objectDiff, err := Objects(sent, received, Options{From: "request", To: "response", Context: 3})
+	if err != nil {
+		log.V(3).Infof("cannot diff objects: %v", err)
+		return
+	}
+	if len(objectDiff) == 0 {
+		log.V(3).Infof("response is identical to request")
+		return
+	}
+	log.V(3).Infof("request/response diff:\n%s", objectDiff)
+}
+
+func withoutManagedFields(object interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if metadata, ok := fields["metadata"].(map[string]interface{}); ok {
+		delete(metadata, "managedFields")
+	}
+	return fields, nil
+}
@@ -0,0 +1,75 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"common/vlog"
+)
+
+type object struct {
+	Metadata map[string]interface{} `json:"metadata"`
+	Replicas int                    `json:"replicas"`
+}
+
+func TestPrintChanged(t *testing.T) {
+	before := object{Metadata: map[string]interface{}{"name": "web", "managedFields": []string{"kubectl"}}, Replicas: 3}
+	after := object{Metadata: map[string]interface{}{"name": "web", "managedFields": []string{"kubectl", "edit-deploy"}}, Replicas: 5}
+
+	var out bytes.Buffer
+	if err := PrintChanged(&out, before, after, "web"); err != nil {
+		t.Fatalf("PrintChanged: %v", err)
+	}
+	want := text("--- before/web", "+++ after/web", "@@ -1,3 +1,3 @@", " metadata:", "   name: web", "-replicas: 3", "+replicas: 5")
+	if out.String() != want {
+		t.Errorf("out =\n%s\nwant\n%s", out.String(), want)
+	}
+
+	//Only managedFields changed
+	out.Reset()
+	after.Replicas = 3
+	if err := PrintChanged(&out, before, after, "web"); err != nil {
+		t.Fatalf("PrintChanged: %v", err)
+	}
+	if got := out.String(); got != "No changes..\n" {
+		t.Errorf("out = %q, want No changes..", got)
+	}
+}
+
+func TestPrintChangedError(t *testing.T) {
+	var out bytes.Buffer
+	if err := PrintChanged(&out, make(chan int), object{}, "web"); err == nil {
+		t.Error("PrintChanged of an unmarshalable object = nil, want an error")
+	}
+	if out.Len() > 0 {
+		t.Errorf("out = %q, want nothing on error", out.String())
+	}
+}
+
+func TestLog(t *testing.T) {
+	sent := object{Replicas: 5}
+
+	var logs bytes.Buffer
+	Log(vlog.New(&logs, 2), sent, object{Replicas: 4})
+	if logs.Len() > 0 {
+		t.Errorf("level 2 logged %q, want nothing", logs.String())
+	}
+
+	Log(vlog.New(&logs, 3), sent, sent)
+	if got := logs.String(); got != "response is identical to request\n" {
+		t.Errorf("log = %q", got)
+	}
+
+	logs.Reset()
+	Log(vlog.New(&logs, 3), sent, object{Replicas: 4})
+	if got := logs.String(); !strings.HasPrefix(got, "request/response diff:\n--- request\n+++ response\n") || !strings.Contains(got, "-replicas: 5\n+replicas: 4\n") {
+		t.Errorf("log = %q", got)
+	}
+
+	logs.Reset()
+	Log(vlog.New(&logs, 3), make(chan int), sent)
+	if got := logs.String(); !strings.HasPrefix(got, "cannot diff objects: ") {
+		t.Errorf("log = %q", got)
+	}
+}
@@ -0,0 +1,70 @@
+//Package exitcode maps command errors to the process exit codes shared by the edit plugins
+//so scripts can tell a missing object apart from a broken connection
+package exitcode
+
+import (
+	"errors"
+	"net"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+const (
+	//Validation and usage errors, also anything not classified below
+	Usage = 1
+	//The target object does not exist
+	NotFound = 2
+	//The user is not allowed to perform the operation
+	Forbidden = 3
+	//The API server could not be reached
+	Connection = 4
+	//The object was changed by someone else since it was read
+	Conflict = 5
+)
+
+//Help text listing the exit codes, appended to the Long description of every command
+const Help = `Exit codes:
+  0  success
+  1  validation or usage error
+  2  object not found
+  3  unauthorized or forbidden
+  4  connection to the API server failed
+  5  conflict, the object was modified concurrently
+When several edits fail, e.g. with --all-namespaces, their shared code is used, mixed failures exit with 1`
+
+//For returns the exit code for err, errors must be wrapped with %w to keep their class
+//An aggregate of several errors gets the code they all share, Usage when they differ
+func For(err error) int {
+	var netErr net.Error
+	var aggregate utilerrors.Aggregate
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &aggregate):
+		return forAll(aggregate.Errors())
+	case apierrors.IsNotFound(err):
+		return NotFound
+	case apierrors.IsConflict(err):
+		return Conflict
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err):
+		return Forbidden
+	case errors.As(err, &netErr):
+		return Connection
+	default:
+		return Usage
+	}
+}
+
+func forAll(errs []error) int {
+	code := 0
+	for i, err := range errs {
+		if i == 0 {
+			code = For(err)
+		} else if For(err) != code {
+			return Usage
+		}
+	}
+	return code
+}
@@ -0,0 +1,55 @@
+package exitcode
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+var deployments = schema.GroupResource{Group: "apps", Resource: "deployments"}
+
+func TestFor(t *testing.T) {
+	notFound := apierrors.NewNotFound(deployments, "web")
+	conflict := apierrors.NewConflict(deployments, "web", errors.New("modified"))
+	forbidden := apierrors.NewForbidden(deployments, "web", errors.New("denied"))
+	unauthorized := apierrors.NewUnauthorized("no token")
+	connection := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, 0},
+		{"usage", errors.New("invalid --replicas"), Usage},
+		{"not found", notFound, NotFound},
+		{"wrapped not found", fmt.Errorf("failed to get deployment: %w", notFound), NotFound},
+		{"conflict", conflict, Conflict},
+		{"forbidden", forbidden, Forbidden},
+		{"unauthorized", unauthorized, Forbidden},
+		{"connection", fmt.Errorf("update failed: %w", connection), Connection},
+		{"aggregate of one class", utilerrors.NewAggregate([]error{
+			fmt.Errorf("namespace team: %w", forbidden),
+			fmt.Errorf("namespace shop: %w", forbidden),
+		}), Forbidden},
+		{"aggregate of mixed classes", utilerrors.NewAggregate([]error{
+			fmt.Errorf("namespace team: %w", notFound),
+			fmt.Errorf("namespace shop: %w", conflict),
+		}), Usage},
+		{"nested aggregate", utilerrors.NewAggregate([]error{
+			conflict,
+			utilerrors.NewAggregate([]error{conflict, conflict}),
+		}), Conflict},
+		{"wrapped aggregate", fmt.Errorf("edit failed: %w", utilerrors.NewAggregate([]error{notFound, notFound})), NotFound},
+	}
+	for _, tt := range tests {
+		if got := For(tt.err); got != tt.want {
+			t.Errorf("For(%s) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+//Package flagerr explains flag errors of the edit plugins
+//Unknown flags get "did you mean" suggestions and missing values the expected syntax of the flag
+package flagerr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+//Annotation holding the value syntax shown when a flag is given without a value
+const SyntaxAnnotation = "edit-plugins/value-syntax"
+
+//At most this many flag names are suggested
+const maxSuggestions = 3
+
+//SetSyntax records the expected value syntax of a registered flag, e.g. "key=value or key-"
+func SetSyntax(flags *pflag.FlagSet, name, syntax string) {
+	if err := flags.SetAnnotation(name, SyntaxAnnotation, []string{syntax}); err != nil {
+		panic(err)
+	}
+}
+
+//Handle adds a hint to err, it is meant as the FlagErrorFunc of a command:
+//  cmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error { return flagerr.Handle(c.Flags(), err) })
+func Handle(flags *pflag.FlagSet, err error) error {
+	message := err.Error()
+
+	switch {
+	case strings.HasPrefix(message, "unknown flag: --"):
+		name := strings.TrimPrefix(message, "unknown flag: --")
+		return withSuggestions(flags, err, name)
+	case strings.HasPrefix(message, "unknown shorthand flag: "):
+		//-replicas is parsed as the shorthand -r, suggest the long flags for the whole word
+		//pflag prints the whole argument, a value given with = is not part of the name
+		if i := strings.LastIndex(message, " in -"); i >= 0 {
+			name := strings.SplitN(message[i+len(" in -"):], "=", 2)[0]
+			return withSuggestions(flags, err, name)
+		}
+	case strings.HasPrefix(message, "flag needs an argument: "):
+		if flag := missingValueFlag(flags, strings.TrimPrefix(message, "flag needs an argument: ")); flag != nil {
+			if syntax := flag.Annotations[SyntaxAnnotation]; len(syntax) > 0 {
+				return fmt.Errorf("%w (expects %s)", err, syntax[0])
+			}
+		}
+	}
+	return err
+}
+
+func withSuggestions(flags *pflag.FlagSet, err error, typo string) error {
+	suggestions := Suggest(flags, typo)
+	if len(suggestions) == 0 {
+		return err
+	}
+	for i, name := range suggestions {
+		suggestions[i] = "--" + name
+	}
+	if len(suggestions) == 1 {
+		return fmt.Errorf("%w, did you mean %s?", err, suggestions[0])
+	}
+	last := len(suggestions) - 1
+	return fmt.Errorf("%w, did you mean %s or %s?", err, strings.Join(suggestions[:last], ", "), suggestions[last])
+}
+
+//Suggest returns up to three visible flag names closest to typo, closest first
+func Suggest(flags *pflag.FlagSet, typo string) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+
+	//Roughly one typo per three characters, at least two
+	limit := len(typo) / 3
+	if limit < 2 {
+		limit = 2
+	}
+
+	var candidates []candidate
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden {
+			return
+		}
+		distance := levenshtein(typo, flag.Name)
+		if distance <= limit || (len(typo) > 2 && strings.HasPrefix(flag.Name, typo)) {
+			candidates = append(candidates, candidate{name: flag.Name, distance: distance})
+		}
+	})
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].name < candidates[j].name
+	})
+
+	var names []string
+	for _, c := range candidates {
+		if len(names) == maxSuggestions {
+			break
+		}
+		names = append(names, c.name)
+	}
+	return names
+}
+
+//The flag of "--name" or "'n' in -n" as printed by pflag
+func missingValueFlag(flags *pflag.FlagSet, flagText string) *pflag.Flag {
+	if strings.HasPrefix(flagText, "--") {
+		return flags.Lookup(strings.TrimPrefix(flagText, "--"))
+	}
+	if len(flagText) >= 3 && flagText[0] == '\'' && flagText[2] == '\'' {
+		return flags.ShorthandLookup(flagText[1:2])
+	}
+	return nil
+}
+
+//Number of single character insertions, deletions and substitutions turning a into b
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func min(values ...int) int {
+	smallest := values[0]
+	for _, value := range values[1:] {
+		if value < smallest {
+			smallest = value
+		}
+	}
+	return smallest
+}
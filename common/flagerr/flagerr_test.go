@@ -0,0 +1,95 @@
+package flagerr
+
+import (
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+//Function to return flags like the ones of edit-deploy
+func testFlags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("edit-deploy", pflag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.Int32("replicas", -1, "")
+	flags.Int32("rhl", -1, "")
+	flags.String("max-surge", "", "")
+	flags.String("max-unavailable", "", "")
+	flags.StringArray("annotation", nil, "")
+	flags.StringArray("label", nil, "")
+	flags.StringP("namespace", "n", "", "")
+	flags.Bool("secret", false, "")
+	flags.Lookup("secret").Hidden = true
+	SetSyntax(flags, "annotation", "key=value or key-")
+	SetSyntax(flags, "namespace", "a namespace name")
+	return flags
+}
+
+func TestHandle(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--maxsurge=1"}, "unknown flag: --maxsurge, did you mean --max-surge?"},
+		{[]string{"--replica=5"}, "unknown flag: --replica, did you mean --replicas?"},
+		{[]string{"--max=1"}, "unknown flag: --max, did you mean --max-surge or --max-unavailable?"},
+		{[]string{"-replicas=5"}, "unknown shorthand flag: 'r' in -replicas=5, did you mean --replicas?"},
+		{[]string{"-max-unavailable=25%"}, "unknown shorthand flag: 'm' in -max-unavailable=25%, did you mean --max-unavailable?"},
+		{[]string{"-replicas"}, "unknown shorthand flag: 'r' in -replicas, did you mean --replicas?"},
+		{[]string{"--image=nginx"}, "unknown flag: --image"},
+		{[]string{"--secrets"}, "unknown flag: --secrets"},
+		{[]string{"--annotation"}, "flag needs an argument: --annotation (expects key=value or key-)"},
+		{[]string{"-n"}, "flag needs an argument: 'n' in -n (expects a namespace name)"},
+		{[]string{"--label"}, "flag needs an argument: --label"},
+		{[]string{"--replicas=many"}, `invalid argument "many" for "--replicas" flag: strconv.ParseInt: parsing "many": invalid syntax`},
+	}
+	for _, tt := range tests {
+		flags := testFlags()
+		err := flags.Parse(tt.args)
+		if err == nil {
+			t.Fatalf("Parse(%v) = nil", tt.args)
+		}
+		if got := Handle(flags, err); got.Error() != tt.want {
+			t.Errorf("Handle(%v) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	tests := []struct {
+		typo string
+		want []string
+	}{
+		{"maxsurge", []string{"max-surge"}},
+		{"labels", []string{"label"}},
+		{"rh", []string{"rhl"}},
+		{"ma", nil},
+		{"m", nil},
+		{"image", nil},
+		{"secret", nil},
+	}
+	for _, tt := range tests {
+		if got := Suggest(testFlags(), tt.typo); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Suggest(%q) = %q, want %q", tt.typo, got, tt.want)
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"rhl", "", 3},
+		{"maxsurge", "max-surge", 1},
+		{"replica", "replicas", 1},
+		{"lable", "label", 2},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
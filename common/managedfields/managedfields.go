@@ -0,0 +1,80 @@
+//Package managedfields prints the managedFields of an object to debug server-side apply conflicts
+package managedfields
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"common/table"
+)
+
+//Print writes one row per manager with the field paths it owns
+func Print(w io.Writer, entries []metav1.ManagedFieldsEntry, wide bool) error {
+	t := table.New()
+	t.AddColumn("manager")
+	t.AddColumn("operation")
+	t.AddColumn("api version")
+	t.AddColumn("subresource", table.WideOnly())
+	t.AddColumn("time")
+	t.AddColumn("fields", table.MaxWidth(80))
+
+	for _, entry := range entries {
+		timestamp := ""
+		if entry.Time != nil {
+			timestamp = entry.Time.UTC().Format("2006-01-02T15:04:05Z")
+		}
+		var fields []string
+		if entry.FieldsV1 != nil {
+			fields = Paths(entry.FieldsV1.Raw)
+		}
+		t.AddRow(entry.Manager, string(entry.Operation), entry.APIVersion, entry.Subresource, timestamp, strings.Join(fields, ","))
+	}
+
+	return t.Render(w, wide)
+}
+
+//Paths flattens a FieldsV1 set into sorted leaf paths such as "spec.replicas" or "spec.rules[0]"
+func Paths(raw []byte) []string {
+	var set map[string]interface{}
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil
+	}
+	var paths []string
+	collect("", set, &paths)
+	sort.Strings(paths)
+	return paths
+}
+
+func collect(prefix string, set map[string]interface{}, paths *[]string) {
+	for key, value := range set {
+		//"." marks the element itself as owned, not only its children
+		if key == "." {
+			continue
+		}
+
+		path := prefix
+		switch {
+		case strings.HasPrefix(key, "f:"):
+			if len(path) > 0 {
+				path += "."
+			}
+			path += key[2:]
+		case strings.HasPrefix(key, "i:"), strings.HasPrefix(key, "k:"), strings.HasPrefix(key, "v:"):
+			path += "[" + key[2:] + "]"
+		default:
+			path += "[" + key + "]"
+		}
+
+		children, ok := value.(map[string]interface{})
+		_, self := children["."]
+		if !ok || len(children) == 0 || (self && len(children) == 1) {
+			*paths = append(*paths, path)
+			continue
+		}
+		collect(path, children, paths)
+	}
+}
@@ -0,0 +1,116 @@
+package managedfields
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPaths(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []string
+	}{
+		{
+			name: "fields",
+			raw:  `{"f:metadata":{"f:labels":{"f:app":{},"f:tier":{}}},"f:spec":{"f:replicas":{}}}`,
+			want: []string{"metadata.labels.app", "metadata.labels.tier", "spec.replicas"},
+		},
+		{
+			name: "atomic list",
+			raw:  `{"f:rules":{}}`,
+			want: []string{"rules"},
+		},
+		{
+			name: "keyed list",
+			raw:  `{"f:spec":{"f:containers":{"k:{\"name\":\"web\"}":{".":{},"f:image":{},"f:name":{}}}}}`,
+			want: []string{`spec.containers[{"name":"web"}].image`, `spec.containers[{"name":"web"}].name`},
+		},
+		{
+			name: "indexed list",
+			raw:  `{"f:spec":{"f:args":{"i:0":{},"i:1":{}}}}`,
+			want: []string{"spec.args[0]", "spec.args[1]"},
+		},
+		{
+			name: "set",
+			raw:  `{"f:metadata":{"f:finalizers":{".":{},"v:\"kubernetes\"":{}}}}`,
+			want: []string{`metadata.finalizers["kubernetes"]`},
+		},
+		{
+			name: "element itself",
+			raw:  `{"f:spec":{"f:template":{".":{}}}}`,
+			want: []string{"spec.template"},
+		},
+		{
+			name: "invalid",
+			raw:  `not json`,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		if got := Paths([]byte(tt.raw)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Paths = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPrint(t *testing.T) {
+	applied := metav1.NewTime(time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC))
+	entries := []metav1.ManagedFieldsEntry{
+		{
+			Manager:    "kubectl-edit-cr",
+			Operation:  metav1.ManagedFieldsOperationApply,
+			APIVersion: "rbac.authorization.k8s.io/v1",
+			Time:       &applied,
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:rules":{}}`)},
+		},
+		{
+			Manager:    "kube-controller-manager",
+			Operation:  metav1.ManagedFieldsOperationUpdate,
+			APIVersion: "rbac.authorization.k8s.io/v1",
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:app":{}},"f:annotations":{".":{},"f:owner":{}}}}`)},
+		},
+		{
+			Manager:     "kubectl",
+			Operation:   metav1.ManagedFieldsOperationUpdate,
+			APIVersion:  "rbac.authorization.k8s.io/v1",
+			Subresource: "status",
+		},
+	}
+
+	var narrow, wide bytes.Buffer
+	if err := Print(&narrow, entries, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := Print(&wide, entries, true); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(narrow.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want a header and one row per manager:\n%s", len(lines), narrow.String())
+	}
+	if got := strings.Fields(lines[0]); !reflect.DeepEqual(got, []string{"MANAGER", "OPERATION", "API-VERSION", "TIME", "FIELDS"}) {
+		t.Errorf("header = %q", got)
+	}
+	for i, want := range [][]string{
+		{"kubectl-edit-cr", "Apply", "rbac.authorization.k8s.io/v1", "2026-10-16T09:30:00Z", "rules"},
+		{"kube-controller-manager", "Update", "rbac.authorization.k8s.io/v1", "metadata.annotations.owner,metadata.labels.app"},
+		{"kubectl", "Update", "rbac.authorization.k8s.io/v1"},
+	} {
+		if got := strings.Fields(lines[i+1]); !reflect.DeepEqual(got, want) {
+			t.Errorf("row %d = %q, want %q", i+1, got, want)
+		}
+	}
+
+	if !strings.Contains(wide.String(), "SUBRESOURCE") || !strings.Contains(wide.String(), "status") {
+		t.Errorf("wide output misses the subresource:\n%s", wide.String())
+	}
+}
@@ -0,0 +1,38 @@
+//Package prompt asks the user for confirmation before a change is applied
+package prompt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+//Confirm prints question and returns true only for an answer of "y" or "yes"
+//An empty answer or end of input counts as no
+//in must be the one reader of the input stream for the whole command, a reader per question
+//would buffer and lose the answers to the following questions
+func Confirm(in *bufio.Reader, out io.Writer, question string) (bool, error) {
+	fmt.Fprintf(out, "%s [y/N]: ", question)
+
+	answer, err := in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+//IsTerminal reports whether w is an interactive terminal, used to decide on colored output
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
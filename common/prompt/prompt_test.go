@@ -0,0 +1,52 @@
+package prompt
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"y\n", true},
+		{"yes\n", true},
+		{" Y \n", true},
+		{"YES", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false},
+		{"sure\n", false},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		got, err := Confirm(bufio.NewReader(strings.NewReader(tt.input)), &out, "Apply?")
+		if err != nil {
+			t.Fatalf("Confirm(%q): %v", tt.input, err)
+		}
+		if got != tt.want {
+			t.Errorf("Confirm(%q) = %t, want %t", tt.input, got, tt.want)
+		}
+		if out.String() != "Apply? [y/N]: " {
+			t.Errorf("Confirm(%q) printed %q", tt.input, out.String())
+		}
+	}
+}
+
+//Every question of a command reads from the same reader, an answer must not be swallowed by the one before
+func TestConfirmSharedReader(t *testing.T) {
+	in := bufio.NewReader(strings.NewReader("y\nn\nyes\n"))
+	var out bytes.Buffer
+	for i, want := range []bool{true, false, true, false} {
+		got, err := Confirm(in, &out, "Apply?")
+		if err != nil {
+			t.Fatalf("question %d: %v", i+1, err)
+		}
+		if got != want {
+			t.Errorf("question %d = %t, want %t", i+1, got, want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+//Package protection reads the protectedNamespaces of the config file shared by the edit plugins
+//Destructive operations in those namespaces need --override-protection and a confirmation
+package protection
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"common/prompt"
+
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+//Environment variable pointing to the config file when --config is not given
+const EnvVar = "EDIT_PLUGINS_CONFIG"
+
+//Config is the content of the config file, e.g.
+//  protectedNamespaces: [kube-system, prod-critical]
+type Config struct {
+	ProtectedNamespaces []string `json:"protectedNamespaces"`
+}
+
+//AddFlags registers --config and --override-protection on flags
+func AddFlags(flags *pflag.FlagSet, path *string, override *bool) {
+	flags.StringVar(path, "config", "", "Config file of the edit plugins, defaults to $"+EnvVar+" or ~/.kube/edit-plugins.yaml")
+	flags.BoolVar(override, "override-protection", false, "Allow destructive operations in the protectedNamespaces of the config file, a confirmation is always asked")
+}
+
+//Load reads the config file at path, or at the default location when path is empty
+//A missing default file is an empty config, a missing file that was asked for is an error
+func Load(path string) (*Config, error) {
+	explicit := len(path) > 0
+	if !explicit {
+		path = defaultPath()
+	}
+	if len(path) == 0 {
+		return &Config{}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return &Config{}, nil
+		}
+		return nil, fmt.Errorf("cannot read config file: %w", err)
+	}
+
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return config, nil
+}
+
+func defaultPath() string {
+	if path := os.Getenv(EnvVar); len(path) > 0 {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".kube", "edit-plugins.yaml")
+}
+
+//Protected reports whether namespace is listed in protectedNamespaces, a nil Config protects nothing
+func (c *Config) Protected(namespace string) bool {
+	if c == nil {
+		return false
+	}
+	for _, protected := range c.ProtectedNamespaces {
+		if protected == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+//Confirm asks before a protected operation, --yes of the plugins deliberately does not skip it
+//target describes where the operations run, e.g. `protected namespace "kube-system"`
+func Confirm(in *bufio.Reader, out io.Writer, target string, override bool, operations []string) (bool, error) {
+	if !override {
+		return false, fmt.Errorf("%s: %s needs --override-protection", target, join(operations))
+	}
+	return prompt.Confirm(in, out, fmt.Sprintf("Run %s in %s?", join(operations), target))
+}
+
+func join(operations []string) string {
+	switch len(operations) {
+	case 0:
+		return "the edit"
+	case 1:
+		return operations[0]
+	}
+	joined := operations[0]
+	for _, operation := range operations[1 : len(operations)-1] {
+		joined += ", " + operation
+	}
+	return joined + " and " + operations[len(operations)-1]
+}
@@ -0,0 +1,137 @@
+package protection
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "edit-plugins.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, "protectedNamespaces: [kube-system, prod-critical]\n")
+	config, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if want := []string{"kube-system", "prod-critical"}; !reflect.DeepEqual(config.ProtectedNamespaces, want) {
+		t.Errorf("ProtectedNamespaces = %q, want %q", config.ProtectedNamespaces, want)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"missing file", filepath.Join(t.TempDir(), "missing.yaml"), "cannot read config file: "},
+		{"unknown field", writeConfig(t, "protectedNamespace: [kube-system]\n"), "invalid config file "},
+		{"wrong type", writeConfig(t, "protectedNamespaces: kube-system\n"), "invalid config file "},
+	}
+	for _, tt := range tests {
+		_, err := Load(tt.path)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%s: Load = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+//Without --config the file of the environment variable is read, and a missing one is an empty config
+func TestLoadDefaultPath(t *testing.T) {
+	t.Setenv(EnvVar, writeConfig(t, "protectedNamespaces: [kube-system]\n"))
+	config, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !config.Protected("kube-system") {
+		t.Errorf("config of $%s was not read: %+v", EnvVar, config)
+	}
+
+	t.Setenv(EnvVar, filepath.Join(t.TempDir(), "missing.yaml"))
+	config, err = Load("")
+	if err != nil {
+		t.Fatalf("Load with a missing default file: %v", err)
+	}
+	if len(config.ProtectedNamespaces) != 0 {
+		t.Errorf("config = %+v, want it empty", config)
+	}
+}
+
+func TestProtected(t *testing.T) {
+	config := &Config{ProtectedNamespaces: []string{"kube-system"}}
+	if !config.Protected("kube-system") {
+		t.Error("kube-system is not protected")
+	}
+	if config.Protected("team") {
+		t.Error("team is protected")
+	}
+	var none *Config
+	if none.Protected("kube-system") {
+		t.Error("a nil config protects kube-system")
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		input    string
+		override bool
+		want     bool
+		wantErr  string
+		question string
+	}{
+		{override: false, wantErr: `protected namespace "kube-system": scaling to 0 and --prune-rules needs --override-protection`},
+		{input: "y\n", override: true, want: true, question: `Run scaling to 0 and --prune-rules in protected namespace "kube-system"? [y/N]: `},
+		{input: "\n", override: true, want: false, question: `Run scaling to 0 and --prune-rules in protected namespace "kube-system"? [y/N]: `},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		got, err := Confirm(bufio.NewReader(strings.NewReader(tt.input)), &out, `protected namespace "kube-system"`, tt.override, []string{"scaling to 0", "--prune-rules"})
+		if len(tt.wantErr) > 0 {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Confirm = %v, want %q", err, tt.wantErr)
+			}
+			if out.Len() > 0 {
+				t.Errorf("Confirm asked without --override-protection: %q", out.String())
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Confirm(%q): %v", tt.input, err)
+		}
+		if got != tt.want {
+			t.Errorf("Confirm(%q) = %t, want %t", tt.input, got, tt.want)
+		}
+		if out.String() != tt.question {
+			t.Errorf("Confirm(%q) printed %q, want %q", tt.input, out.String(), tt.question)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		operations []string
+		want       string
+	}{
+		{nil, "the edit"},
+		{[]string{"scaling to 0"}, "scaling to 0"},
+		{[]string{"scaling to 0", "--prune-rules"}, "scaling to 0 and --prune-rules"},
+		{[]string{"scaling to 0", "--prune-rules", "--pre-hook"}, "scaling to 0, --prune-rules and --pre-hook"},
+	}
+	for _, tt := range tests {
+		if got := join(tt.operations); got != tt.want {
+			t.Errorf("join(%q) = %q, want %q", tt.operations, got, tt.want)
+		}
+	}
+}
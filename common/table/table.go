@@ -0,0 +1,157 @@
+//Package table is the shared printer for every list-style output of the edit plugins
+//so both binaries use the same header casing, truncation and json layout
+package table
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+)
+
+//Marker placed in the middle of cells that are too long for their column
+const ellipsis = "..."
+
+type column struct {
+	header   string
+	key      string
+	wideOnly bool
+	maxWidth int
+}
+
+//ColumnOption changes how a single column is printed
+type ColumnOption func(*column)
+
+//WideOnly hides the column unless the table is rendered in wide mode
+func WideOnly() ColumnOption {
+	return func(c *column) {
+		c.wideOnly = true
+	}
+}
+
+//MaxWidth middle-truncates cells longer than n characters (e.g. long image strings)
+//Wide mode always prints the full value
+func MaxWidth(n int) ColumnOption {
+	return func(c *column) {
+		c.maxWidth = n
+	}
+}
+
+//Table collects columns and rows and renders them either aligned or as json
+type Table struct {
+	columns []column
+	rows    [][]string
+}
+
+//Function to return an empty table
+func New() *Table {
+	return &Table{}
+}
+
+//AddColumn registers a column, the header is printed upper case with "-" between words
+//e.g. "api groups" becomes "API-GROUPS" and is keyed as "apiGroups" in json
+func (t *Table) AddColumn(name string, opts ...ColumnOption) {
+	c := column{header: header(name), key: jsonKey(name)}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	t.columns = append(t.columns, c)
+}
+
+//AddRow appends one row, there must be exactly one cell per column
+func (t *Table) AddRow(cells ...string) {
+	if len(cells) != len(t.columns) {
+		panic(fmt.Sprintf("table: row has %d cells but table has %d columns", len(cells), len(t.columns)))
+	}
+	t.rows = append(t.rows, cells)
+}
+
+//Len returns the number of rows added so far
+func (t *Table) Len() int {
+	return len(t.rows)
+}
+
+//Render writes the aligned table to w
+//Without wide, WideOnly columns are skipped and MaxWidth columns are truncated
+func (t *Table) Render(w io.Writer, wide bool) error {
+	tw := tabwriter.NewWriter(w, 6, 4, 3, ' ', 0)
+
+	var visible []int
+	for i, c := range t.columns {
+		if c.wideOnly && !wide {
+			continue
+		}
+		visible = append(visible, i)
+	}
+
+	headers := make([]string, 0, len(visible))
+	for _, i := range visible {
+		headers = append(headers, t.columns[i].header)
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+
+	for _, row := range t.rows {
+		cells := make([]string, 0, len(visible))
+		for _, i := range visible {
+			cell := row[i]
+			if !wide {
+				cell = truncateMiddle(cell, t.columns[i].maxWidth)
+			}
+			cells = append(cells, cell)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+//RenderJSON writes every row as a json object keyed by column, nothing is hidden or truncated
+func (t *Table) RenderJSON(w io.Writer) error {
+	objects := make([]map[string]string, 0, len(t.rows))
+	for _, row := range t.rows {
+		object := make(map[string]string, len(t.columns))
+		for i, c := range t.columns {
+			object[c.key] = row[i]
+		}
+		objects = append(objects, object)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(objects)
+}
+
+//Keep the start and the end of the value since both ends of an image string matter (registry and tag)
+func truncateMiddle(s string, max int) string {
+	r := []rune(s)
+	if max <= 0 || len(r) <= max {
+		return s
+	}
+	if max <= len(ellipsis) {
+		return string(r[:max])
+	}
+	keep := max - len(ellipsis)
+	head := (keep + 1) / 2
+	tail := keep - head
+	return string(r[:head]) + ellipsis + string(r[len(r)-tail:])
+}
+
+func header(name string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(name), "-"))
+}
+
+func jsonKey(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '-' || r == '_'
+	})
+	for i, word := range words {
+		word = strings.ToLower(word)
+		if i > 0 {
+			word = strings.ToUpper(word[:1]) + word[1:]
+		}
+		words[i] = word
+	}
+	return strings.Join(words, "")
+}
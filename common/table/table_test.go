@@ -0,0 +1,122 @@
+package table
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+//Run go test ./table -update to rewrite the golden files after an intended change
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func newTestTable() *Table {
+	t := New()
+	t.AddColumn("name")
+	t.AddColumn("api groups")
+	t.AddColumn("image", MaxWidth(20))
+	t.AddColumn("node name", WideOnly())
+	t.AddRow("web", "apps", "registry.example.com/team/web:v1.2.3", "node-a")
+	t.AddRow("db", "", "postgres:14", "node-b")
+	return t
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name   string
+		golden string
+		render func(tbl *Table, buf *bytes.Buffer) error
+	}{
+		{
+			name:   "narrow truncates and hides wide columns",
+			golden: "narrow.golden",
+			render: func(tbl *Table, buf *bytes.Buffer) error { return tbl.Render(buf, false) },
+		},
+		{
+			name:   "wide shows everything",
+			golden: "wide.golden",
+			render: func(tbl *Table, buf *bytes.Buffer) error { return tbl.Render(buf, true) },
+		},
+		{
+			name:   "json keys by lower camel column name",
+			golden: "json.golden",
+			render: func(tbl *Table, buf *bytes.Buffer) error { return tbl.RenderJSON(buf) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.render(newTestTable(), &buf); err != nil {
+				t.Fatalf("render failed: %v", err)
+			}
+			assertGolden(t, tt.golden, buf.Bytes())
+		})
+	}
+}
+
+func TestHeaderAndKey(t *testing.T) {
+	tests := []struct {
+		name, header, key string
+	}{
+		{"name", "NAME", "name"},
+		{"api groups", "API-GROUPS", "apiGroups"},
+		{"non resource urls", "NON-RESOURCE-URLS", "nonResourceUrls"},
+	}
+	for _, tt := range tests {
+		if got := header(tt.name); got != tt.header {
+			t.Errorf("header(%q) = %q, want %q", tt.name, got, tt.header)
+		}
+		if got := jsonKey(tt.name); got != tt.key {
+			t.Errorf("jsonKey(%q) = %q, want %q", tt.name, got, tt.key)
+		}
+	}
+}
+
+func TestTruncateMiddle(t *testing.T) {
+	tests := []struct {
+		value string
+		max   int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"no-limit-at-all", 0, "no-limit-at-all"},
+		{"registry.example.com/web:v1", 11, "regi...b:v1"},
+		{"abcdef", 3, "abc"},
+	}
+	for _, tt := range tests {
+		if got := truncateMiddle(tt.value, tt.max); got != tt.want {
+			t.Errorf("truncateMiddle(%q, %d) = %q, want %q", tt.value, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestAddRowPanicsOnCellCount(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("AddRow with a missing cell did not panic")
+		}
+	}()
+	tbl := New()
+	tbl.AddColumn("name")
+	tbl.AddColumn("rules")
+	tbl.AddRow("only-one")
+}
+
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("cannot write golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("cannot read golden file: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
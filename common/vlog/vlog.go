@@ -0,0 +1,74 @@
+//Package vlog is a small leveled logger for the edit plugins
+//Messages go to the command's ErrOut so the normal output stays unchanged at level 0
+package vlog
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+//Logger prints messages up to the configured level, a nil Logger prints nothing
+type Logger struct {
+	out   io.Writer
+	level int
+}
+
+//Function to return a logger writing to out at the given level
+func New(out io.Writer, level int) *Logger {
+	return &Logger{out: out, level: level}
+}
+
+//AddFlags registers -v/--v on flags, --verbose is accepted as an alias
+func AddFlags(flags *pflag.FlagSet, level *int) {
+	flags.IntVarP(level, "v", "v", 0, "Log level written to stderr (alias --verbose), -v alone is level 1 and higher levels are given as -v=2: 1 resolved target, fetched object and computed change, 2 API calls and retries, 3 object diffs")
+	//A bare -v or --verbose turns on level 1, so a level must be attached with =, e.g. -v=2
+	flags.Lookup("v").NoOptDefVal = "1"
+	flags.SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "verbose" {
+			name = "v"
+		}
+		return pflag.NormalizedName(name)
+	})
+}
+
+//Verbose is returned by V and only prints when the level is enabled
+type Verbose struct {
+	logger  *Logger
+	enabled bool
+}
+
+//V reports whether messages at level should be printed
+//  level 1: resolved context, namespace and target, fetched object and computed change
+//  level 2: every API call and retry attempt with its duration
+//  level 3: request and response objects
+func (l *Logger) V(level int) Verbose {
+	return Verbose{logger: l, enabled: l != nil && l.out != nil && level <= l.level}
+}
+
+//Enabled is used to skip building expensive messages
+func (v Verbose) Enabled() bool {
+	return v.enabled
+}
+
+//Infof prints one message, a trailing newline is added when missing
+func (v Verbose) Infof(format string, args ...interface{}) {
+	if !v.enabled {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	if !strings.HasSuffix(msg, "\n") {
+		msg += "\n"
+	}
+	fmt.Fprint(v.logger.out, msg)
+}
+
+//ErrOrOK returns the error text for a log line, "ok" when err is nil
+func ErrOrOK(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return "ok"
+}
@@ -0,0 +1,91 @@
+package vlog
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestLevels(t *testing.T) {
+	messages := map[int]string{
+		1: "using context \"dev\"",
+		2: "GET deployment team/web (3ms)",
+		3: "request/response diff",
+	}
+
+	for level := 0; level <= 3; level++ {
+		var out bytes.Buffer
+		log := New(&out, level)
+		for v := 1; v <= 3; v++ {
+			log.V(v).Infof("%s", messages[v])
+		}
+
+		for v := 1; v <= 3; v++ {
+			printed := strings.Contains(out.String(), messages[v])
+			if want := v <= level; printed != want {
+				t.Errorf("level %d: message of V(%d) printed=%t, want %t\noutput:\n%s", level, v, printed, want, out.String())
+			}
+		}
+		if level == 0 && out.Len() > 0 {
+			t.Errorf("level 0 printed %q, want nothing", out.String())
+		}
+	}
+}
+
+func TestInfofAddsNewline(t *testing.T) {
+	var out bytes.Buffer
+	log := New(&out, 1)
+	log.V(1).Infof("first")
+	log.V(1).Infof("second\n")
+	if got, want := out.String(), "first\nsecond\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestNilLogger(t *testing.T) {
+	var log *Logger
+	if log.V(1).Enabled() {
+		t.Error("nil logger reports V(1) as enabled")
+	}
+	//Must not panic
+	log.V(1).Infof("ignored")
+}
+
+func TestAddFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{nil, 0},
+		{[]string{"-v"}, 1},
+		{[]string{"--verbose"}, 1},
+		{[]string{"--v"}, 1},
+		{[]string{"-v=2"}, 2},
+		{[]string{"--verbose=2"}, 2},
+		{[]string{"--v=3"}, 3},
+	}
+	for _, tt := range tests {
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		var level int
+		AddFlags(flags, &level)
+		if err := flags.Parse(tt.args); err != nil {
+			t.Errorf("Parse(%v): %v", tt.args, err)
+			continue
+		}
+		if level != tt.want {
+			t.Errorf("Parse(%v) level = %d, want %d", tt.args, level, tt.want)
+		}
+	}
+}
+
+func TestErrOrOK(t *testing.T) {
+	if got := ErrOrOK(nil); got != "ok" {
+		t.Errorf("ErrOrOK(nil) = %q, want ok", got)
+	}
+	if got := ErrOrOK(errors.New("connection refused")); got != "connection refused" {
+		t.Errorf("ErrOrOK(err) = %q, want the error text", got)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+//Function to validate --aggregate-selector
+//Rules of an aggregated ClusterRole are filled in by the controller from the matched roles, so they cannot be set in the same call
+func (o *EditDeployOptions) validateAggregateSelector() error {
+	if len(o.newVerbs) > 0 || len(o.newResources) > 0 || len(o.newApiGroups) > 0 {
+		return fmt.Errorf("--aggregate-selector cannot be combined with --verbs, --resources or --groups, the rules of an aggregated ClusterRole are computed from the matched ClusterRoles")
+	}
+	if o.serverSide {
+		return fmt.Errorf("--aggregate-selector does not support --server-side")
+	}
+
+	for _, key := range o.aggregateSelectorKeys() {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid --aggregate-selector key %q: %s", key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(o.aggregateSelector[key]); len(errs) > 0 {
+			return fmt.Errorf("invalid --aggregate-selector value %q for key %q: %s", o.aggregateSelector[key], key, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+//Function to append the selector given by --aggregate-selector, the aggregationRule is created if needed
+func (o *EditDeployOptions) appendAggregateSelector(clusterRole *v1.ClusterRole) {
+	matchLabels := make(map[string]string, len(o.aggregateSelector))
+	for key, value := range o.aggregateSelector {
+		matchLabels[key] = value
+	}
+
+	if clusterRole.AggregationRule == nil {
+		clusterRole.AggregationRule = &v1.AggregationRule{}
+	}
+	clusterRole.AggregationRule.ClusterRoleSelectors = append(clusterRole.AggregationRule.ClusterRoleSelectors, metav1.LabelSelector{MatchLabels: matchLabels})
+}
+
+//Sorted keys of --aggregate-selector so errors and logs are stable
+func (o *EditDeployOptions) aggregateSelectorKeys() []string {
+	keys := make([]string, 0, len(o.aggregateSelector))
+	for key := range o.aggregateSelector {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
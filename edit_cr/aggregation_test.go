@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func storedAggregationRule(t *testing.T, r *testRun) *v1.AggregationRule {
+	t.Helper()
+	stored, err := r.clientset.RbacV1().ClusterRoles().Get(context.TODO(), "reader", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stored.AggregationRule
+}
+
+func TestAggregateSelectorValidation(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--aggregate-selector=team=web", "--verbs=get"}, "--aggregate-selector cannot be combined with --verbs, --resources or --groups, the rules of an aggregated ClusterRole are computed from the matched ClusterRoles"},
+		{[]string{"--aggregate-selector=team=web", "--groups=apps"}, "--aggregate-selector cannot be combined with --verbs, --resources or --groups"},
+		{[]string{"--aggregate-selector=team=web", "--server-side"}, "--aggregate-selector does not support --server-side"},
+		{[]string{"--aggregate-selector=bad key=web"}, `invalid --aggregate-selector key "bad key": `},
+		{[]string{"--aggregate-selector=team=web app"}, `invalid --aggregate-selector value "web app" for key "team": `},
+	}
+	for _, tt := range tests {
+		r := newTestRun(t, testClusterRole())
+		err := r.run(append([]string{"reader"}, tt.args...)...)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("run(%q) = %v, want %q", tt.args, err, tt.want)
+		}
+		if rule := storedAggregationRule(t, r); rule != nil {
+			t.Errorf("run(%q) changed the aggregationRule to %v", tt.args, rule)
+		}
+	}
+}
+
+//A ClusterRole without an aggregationRule gets one holding only the new selector
+func TestAggregateSelectorCreatesRule(t *testing.T) {
+	r := newTestRun(t, testClusterRole())
+	if err := r.run("reader", "--aggregate-selector=rbac.example.com/aggregate-to-reader=true,team=web"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	want := &v1.AggregationRule{ClusterRoleSelectors: []metav1.LabelSelector{
+		{MatchLabels: map[string]string{"rbac.example.com/aggregate-to-reader": "true", "team": "web"}},
+	}}
+	if got := storedAggregationRule(t, r); !reflect.DeepEqual(got, want) {
+		t.Errorf("aggregationRule = %v, want %v", got, want)
+	}
+	if got := storedRules(t, r); !reflect.DeepEqual(got, testClusterRole().Rules) {
+		t.Errorf("rules = %v, want them unchanged", got)
+	}
+	if got, want := r.out.String(), "Updated ClusterRoles..\n"; got != want {
+		t.Errorf("out = %q, want %q", got, want)
+	}
+}
+
+//Existing selectors are kept, the new one is appended after them
+func TestAggregateSelectorAppendsToRule(t *testing.T) {
+	existing := metav1.LabelSelector{MatchLabels: map[string]string{"team": "shop"}}
+	clusterRole := testClusterRole()
+	clusterRole.AggregationRule = &v1.AggregationRule{ClusterRoleSelectors: []metav1.LabelSelector{existing}}
+
+	r := newTestRun(t, clusterRole)
+	if err := r.run("reader", "--aggregate-selector=team=web"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	want := &v1.AggregationRule{ClusterRoleSelectors: []metav1.LabelSelector{
+		existing,
+		{MatchLabels: map[string]string{"team": "web"}},
+	}}
+	if got := storedAggregationRule(t, r); !reflect.DeepEqual(got, want) {
+		t.Errorf("aggregationRule = %v, want %v", got, want)
+	}
+}
@@ -3,11 +3,13 @@ module edit_cr
 go 1.18
 
 require (
+	common v0.0.0-00010101000000-000000000000
 	github.com/spf13/cobra v1.4.0
 	k8s.io/api v0.24.1
 	k8s.io/apimachinery v0.24.1
 	k8s.io/cli-runtime v0.24.1
 	k8s.io/client-go v0.24.1
+	sigs.k8s.io/yaml v1.2.0
 )
 
 require (
@@ -64,5 +66,6 @@ require (
 	sigs.k8s.io/kustomize/api v0.11.4 // indirect
 	sigs.k8s.io/kustomize/kyaml v0.13.6 // indirect
 	sigs.k8s.io/structured-merge-diff/v4 v4.2.1 // indirect
-	sigs.k8s.io/yaml v1.2.0 // indirect
 )
+
+replace common => ../common
@@ -1,16 +1,29 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"flag"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"common/apicheck"
+	"common/apiserver"
+	"common/diff"
+	"common/exitcode"
+	"common/flagerr"
+	"common/managedfields"
+	"common/protection"
+	"common/vlog"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/kubernetes"
 
 	v1 "k8s.io/api/rbac/v1"
@@ -27,21 +40,88 @@ var (
 	#--groups = specify groups that resources belongs to seperated by ","
 	%[1]s edit-cr <clusterResourceName> --verbs=update,delete --resources=downloads,links --groups=data.falcon.io
 	
+	#--server-side = append the rule with server-side apply so field ownership is tracked
+	%[1]s edit-cr <clusterResourceName> --verbs=get --resources=links --groups=data.falcon.io --server-side --field-manager=platform-team
+	
+	#--show-diff = print what the update changed once it succeeded
+	%[1]s edit-cr <clusterResourceName> --verbs=get --resources=links --groups=data.falcon.io --show-diff
+	
+	#--prune-rules = replace all existing rules with the given one
+	%[1]s edit-cr <clusterResourceName> --verbs=get,list --resources=links --groups=data.falcon.io --prune-rules
+	
+	#--prune-unused = list the rules not used by the bound subjects in an audit log, --apply removes them, -o json prints the report as json
+	#--apply refuses to remove every rule or to prune a ClusterRole no binding references unless --allow-empty is given
+	%[1]s edit-cr <clusterResourceName> --prune-unused --audit-file=/var/log/kubernetes/audit.log --apply
+	
+	#--override-protection = allow --prune-rules on a ClusterRole bound in the protectedNamespaces of --config, always asks first
+	%[1]s edit-cr <clusterResourceName> --verbs=get --resources=links --groups=data.falcon.io --prune-rules --override-protection
+	
+	#list-cr = print the rules of a ClusterRole, or all ClusterRoles without a name
+	%[1]s edit-cr list-cr <clusterResourceName>
+	
+	#--apiserver = try these API server endpoints in order and use the first one responding
+	%[1]s edit-cr <clusterResourceName> --verbs=get --resources=links --apiserver=https://10.0.0.1:6443 --apiserver=https://10.0.0.2:6443
+	
+	#--aggregate-selector = aggregate the rules of every ClusterRole with these labels, cannot be combined with --verbs/--resources
+	%[1]s edit-cr <clusterResourceName> --aggregate-selector=rbac.falcon.io/aggregate-to-monitoring=true
+	
 	`
 )
 
+//Field manager used by --server-side unless --field-manager is given
+const defaultFieldManager = "kubectl-edit-cr"
+
 //Struct having all the flags arguments variable
 type EditDeployOptions struct {
 	configFlags *genericclioptions.ConfigFlags
 
 	clusterRoleInterface typev1.ClusterRoleInterface
+	rbacClient           typev1.RbacV1Interface
+	discoveryClient      discovery.DiscoveryInterface
 	newVerbs             string
 	newApiGroups         string
 	newResources         string
 	clusterRoleName      string
 
+	serverSide     bool
+	fieldManager   string
+	forceConflicts bool
+
+	showManagedFields bool
+	//Print what the update changed after it succeeded
+	showChanges bool
+
+	//Replace every existing rule with the new one instead of appending
+	pruneRules bool
+
+	//Remove the rules no request in the audit log used, only printed unless applyPrune
+	pruneUnused bool
+	auditFile   string
+	applyPrune  bool
+	//Allow --apply to leave the ClusterRole without rules
+	allowEmpty bool
+	//"", "wide" or "json" for the --prune-unused report
+	output string
+
+	//protectedNamespaces of the config file and whether replacing rules granted there is allowed
+	configPath         string
+	protection         *protection.Config
+	overrideProtection bool
+
+	//Labels of the selector appended to aggregationRule.clusterRoleSelectors
+	aggregateSelector map[string]string
+
+	//Endpoints of --apiserver, the first one responding is used
+	apiservers []string
+
+	verbosity int
+	log       *vlog.Logger
+
 	args []string
 
+	//Reader of In shared by every confirmation, so answers given ahead are not lost
+	answers *bufio.Reader
+
 	genericclioptions.IOStreams
 }
 
@@ -49,6 +129,7 @@ type EditDeployOptions struct {
 func NewEditDeploymentOptions(streams genericclioptions.IOStreams) *EditDeployOptions {
 	return &EditDeployOptions{
 		configFlags: genericclioptions.NewConfigFlags(true),
+		answers:     bufio.NewReader(streams.In),
 		IOStreams:   streams,
 	}
 }
@@ -56,13 +137,19 @@ func NewEditDeploymentOptions(streams genericclioptions.IOStreams) *EditDeployOp
 //Cobra provides easy cli interface with error handling and easy extensibility(aliases, suggestions, depreciated, etc.) of cli tools
 //https://cobra.dev/
 func NewCmdEdit(streams genericclioptions.IOStreams) *cobra.Command {
-	o := NewEditDeploymentOptions(streams)
+	return newCmdEdit(NewEditDeploymentOptions(streams))
+}
 
+//Command bound to the given options, tests parse flags into their own options
+func newCmdEdit(o *EditDeployOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:          "edit-cr [ClusterRoleName] [flags]",
 		Short:        "Append rules to Specified ClusterRole",
+		Long:         "Append rules to Specified ClusterRole\n\n" + exitcode.Help,
 		Example:      fmt.Sprintf(editExample, "kubectl"),
 		SilenceUsage: true,
+		//The ClusterRole name is checked in Validate, without this cobra reports it as an unknown subcommand
+		Args: cobra.ArbitraryArgs,
 		//RunE function runs when .execute is called with error handling
 		RunE: func(c *cobra.Command, args []string) error {
 			if err := o.Complete(c, args); err != nil {
@@ -84,15 +171,82 @@ func NewCmdEdit(streams genericclioptions.IOStreams) *cobra.Command {
 	cmd.Flags().StringVar(&o.newVerbs, "verbs", o.newVerbs, "Comma seperated verb actions")
 	cmd.Flags().StringVar(&o.newApiGroups, "groups", o.newApiGroups, "comma seperated api groups")
 	cmd.Flags().StringVar(&o.newResources, "resources", o.newResources, "comma seperated Resources")
+	cmd.Flags().BoolVar(&o.serverSide, "server-side", false, "Append the rule with server-side apply instead of get and update")
+	cmd.Flags().StringVar(&o.fieldManager, "field-manager", defaultFieldManager, "Name of the manager owning the rules when using --server-side")
+	cmd.Flags().BoolVar(&o.forceConflicts, "force-conflicts", false, "With --server-side, take over the rules even if another manager owns them")
+	cmd.Flags().BoolVar(&o.showChanges, "show-diff", false, "Print the diff of the ClusterRole before and after the update once it succeeded")
+	cmd.Flags().BoolVar(&o.showManagedFields, "show-managed-fields", false, "Print the managedFields of the ClusterRole on conflicts, and after every change with --v=3")
+	cmd.Flags().BoolVar(&o.pruneRules, "prune-rules", false, "Remove all existing rules so the ClusterRole only has the rule given by --verbs, --resources and --groups")
+	cmd.Flags().BoolVar(&o.pruneUnused, "prune-unused", false, "Find the rules no request of the bound subjects in --audit-file used, add --apply to remove them")
+	cmd.Flags().StringVar(&o.auditFile, "audit-file", "", "Kubernetes audit log in json lines format read by --prune-unused")
+	cmd.Flags().StringVarP(&o.output, "output", "o", "", "Format of the --prune-unused report, \"wide\" or \"json\"")
+	cmd.Flags().BoolVar(&o.applyPrune, "apply", false, "Remove the unused rules found by --prune-unused instead of only printing them")
+	cmd.Flags().BoolVar(&o.allowEmpty, "allow-empty", false, "Let --apply remove every rule, also when no binding references the ClusterRole")
+	cmd.Flags().StringToStringVar(&o.aggregateSelector, "aggregate-selector", nil, "Append a selector matching these key=value labels to the aggregationRule, comma seperated")
+	protection.AddFlags(cmd.Flags(), &o.configPath, &o.overrideProtection)
+	apiserver.AddFlags(cmd.Flags(), &o.apiservers)
+	vlog.AddFlags(cmd.Flags(), &o.verbosity)
 
 	//Add extra flags provided by user
 	o.configFlags.AddFlags(cmd.Flags())
+
+	//Value syntax printed when one of these flags is given without a value
+	flagerr.SetSyntax(cmd.Flags(), "verbs", "comma seperated verbs, e.g. get,list,watch")
+	flagerr.SetSyntax(cmd.Flags(), "resources", "comma seperated resources, e.g. pods,pods/log")
+	flagerr.SetSyntax(cmd.Flags(), "groups", "comma seperated api groups, \"\" is the core group")
+	flagerr.SetSyntax(cmd.Flags(), "aggregate-selector", "key=value labels, comma seperated")
+	flagerr.SetSyntax(cmd.Flags(), "audit-file", "path of a json lines audit log")
+	//Subcommands inherit it, so list-cr gets the suggestions too
+	cmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		return flagerr.Handle(c.Flags(), err)
+	})
+
+	//Subcommands share the binary, a completion command would shadow a ClusterRole of that name
+	cmd.CompletionOptions.DisableDefaultCmd = true
+	cmd.AddCommand(NewCmdListCR(o.IOStreams))
 	return cmd
 }
 
 //Function to store all flags and arguments in struct
 func (o *EditDeployOptions) Complete(cmd *cobra.Command, args []string) error {
+	if err := o.completeFlags(args); err != nil {
+		return err
+	}
+
+	config, err := o.configFlags.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+
+	if config, err = apiserver.Resolve(config, *o.configFlags.APIServer, o.apiservers, o.log); err != nil {
+		return err
+	}
+
+	//Context is only resolved for the log, ClusterRoles are not namespaced
+	if o.log.V(1).Enabled() {
+		contextName := *o.configFlags.Context
+		if len(contextName) == 0 {
+			if rawconfig, err := o.configFlags.ToRawKubeConfigLoader().RawConfig(); err == nil {
+				contextName = rawconfig.CurrentContext
+			}
+		}
+		o.log.V(1).Infof("using context %q", contextName)
+	}
+
+	//Create a new client instance for config
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	o.completeClient(clientset)
+	return nil
+}
+
+//Function to store the flags and arguments that need no cluster access
+func (o *EditDeployOptions) completeFlags(args []string) error {
 	o.args = args
+	o.log = vlog.New(o.ErrOut, o.verbosity)
 
 	if len(args) > 0 {
 		o.clusterRoleName = args[0]
@@ -104,21 +258,22 @@ func (o *EditDeployOptions) Complete(cmd *cobra.Command, args []string) error {
 
 	}
 
-	config, err := o.configFlags.ToRESTConfig()
+	protectionConfig, err := protection.Load(o.configPath)
 	if err != nil {
 		return err
 	}
+	o.protection = protectionConfig
+	return nil
+}
 
-	//Create a new client instance for config
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return err
-	}
+//Function to store the clients of the clientset, tests pass a fake one
+func (o *EditDeployOptions) completeClient(clientset kubernetes.Interface) {
+	o.log.V(1).Infof("target clusterrole %s", o.clusterRoleName)
 
 	//Get ClusterRole Interface
 	o.clusterRoleInterface = clientset.RbacV1().ClusterRoles()
-
-	return nil
+	o.rbacClient = clientset.RbacV1()
+	o.discoveryClient = clientset.Discovery()
 }
 
 //Function to validate if the arguments and flags are correct
@@ -127,6 +282,35 @@ func (o *EditDeployOptions) Validate() error {
 		return fmt.Errorf("only one argument is allowed")
 	}
 
+	if o.forceConflicts && !o.serverSide {
+		return fmt.Errorf("--force-conflicts only applies to --server-side")
+	}
+
+	if o.serverSide && len(o.fieldManager) == 0 {
+		return fmt.Errorf("--field-manager must not be empty")
+	}
+
+	if err := o.validatePruneUnused(); err != nil {
+		return err
+	}
+	if o.pruneUnused {
+		return nil
+	}
+
+	if o.pruneRules {
+		if len(o.aggregateSelector) > 0 {
+			return fmt.Errorf("--prune-rules cannot be combined with --aggregate-selector")
+		}
+		//Pruning without a replacement leaves a ClusterRole granting nothing
+		if len(o.newVerbs) == 0 || len(o.newResources) == 0 {
+			return fmt.Errorf("--prune-rules needs --verbs and --resources, removing every rule without a replacement would grant no permissions")
+		}
+	}
+
+	if len(o.aggregateSelector) > 0 {
+		return o.validateAggregateSelector()
+	}
+
 	if len(o.newVerbs) == 0 {
 		return fmt.Errorf("verb feild is empty")
 	}
@@ -140,6 +324,18 @@ func (o *EditDeployOptions) Validate() error {
 
 //Function to update the deployments
 func (o *EditDeployOptions) Run() error {
+	//Pre-flight before any change is computed
+	proceed, err := o.checkProtection()
+	if err != nil || !proceed {
+		return err
+	}
+
+	if o.serverSide {
+		return o.runServerSide()
+	}
+	if o.pruneUnused {
+		return o.runPruneUnused()
+	}
 
 	//RetryOnConflict make an update to a resource when other code also doing change at same time
 	//If conflict occurs it will wait for sometime
@@ -150,41 +346,115 @@ func (o *EditDeployOptions) Run() error {
 	// 	Jitter:   0.1,
 	// }
 	//https://pkg.go.dev/k8s.io/apimachinery/pkg/util/wait#Backoff
+	attempt := 0
+	removed := 0
+	//The ClusterRole as fetched and as returned by the last attempt, for --show-diff
+	var before, updated *v1.ClusterRole
 	retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		attempt++
+		o.log.V(2).Infof("update attempt %d", attempt)
 
 		//Get the specified ClusterRole
 		//passing empty context
 		//Since no information required for Get like deadline, cancellation etc.
 
+		start := time.Now()
 		result, getErr := o.clusterRoleInterface.Get(context.TODO(), o.clusterRoleName, metav1.GetOptions{})
+		o.log.V(2).Infof("GET clusterrole %s (%v)", o.clusterRoleName, time.Since(start))
 
 		if getErr != nil {
-			return fmt.Errorf("failed to get latest version fo Deployment: %v", getErr)
+			return fmt.Errorf("failed to get latest version fo Deployment: %w", getErr)
+		}
+		o.log.V(1).Infof("fetched clusterrole %s resourceVersion %s with %d rules", o.clusterRoleName, result.ResourceVersion, len(result.Rules))
+		before = result.DeepCopy()
+
+		if len(o.aggregateSelector) > 0 {
+			o.log.V(1).Infof("computed change: append aggregation selector %v", o.aggregateSelector)
+			o.appendAggregateSelector(result)
+		} else {
+			rule := o.newRule()
+			if o.pruneRules {
+				removed = len(result.Rules)
+				o.log.V(1).Infof("computed change: remove %d rules", removed)
+				result.Rules = nil
+			}
+			o.log.V(1).Infof("computed change: append rule verbs=%v resources=%v apiGroups=%v", rule.Verbs, rule.Resources, rule.APIGroups)
+			result.Rules = append(result.Rules, rule)
 		}
 
-		listVerbs := strings.Split(o.newVerbs, ",")
-		listResources := strings.Split(o.newResources, ",")
-		listApiGroups := strings.Split(o.newApiGroups, ",")
-		result.Rules = append(result.Rules, v1.PolicyRule{Verbs: listVerbs, Resources: listResources, APIGroups: listApiGroups})
-
-		_, updateErr := o.clusterRoleInterface.Update(context.TODO(), result, metav1.UpdateOptions{})
+		start = time.Now()
+		var updateErr error
+		updated, updateErr = o.clusterRoleInterface.Update(context.TODO(), result, metav1.UpdateOptions{})
+		o.log.V(2).Infof("PUT clusterrole %s (%v): %v", o.clusterRoleName, time.Since(start), vlog.ErrOrOK(updateErr))
+		if updateErr == nil {
+			diff.Log(o.log, result, updated)
+			o.printManagedFields(updated.ManagedFields, false)
+		}
 		return updateErr
 	})
 
 	if retryErr != nil {
-		return fmt.Errorf("update failed: %v", retryErr)
+		o.printManagedFieldsOnConflict(retryErr)
+		return fmt.Errorf("update failed: %w", apicheck.Unavailable(o.discoveryClient, v1.SchemeGroupVersion, retryErr))
+	}
+	if o.pruneRules {
+		fmt.Fprintf(o.Out, "Updated ClusterRoles.. %d rules removed, 1 added\n", removed)
+	} else {
+		fmt.Fprintln(o.Out, "Updated ClusterRoles..")
 	}
-	fmt.Println("Updated ClusterRoles..")
+	o.printChanges(before, updated)
 
 	return nil
 }
 
+//Function to build the rule given by --verbs, --resources and --groups
+func (o *EditDeployOptions) newRule() v1.PolicyRule {
+	listVerbs := strings.Split(o.newVerbs, ",")
+	listResources := strings.Split(o.newResources, ",")
+	listApiGroups := strings.Split(o.newApiGroups, ",")
+	return v1.PolicyRule{Verbs: listVerbs, Resources: listResources, APIGroups: listApiGroups}
+}
+
+//Function to print who owns which fields of the ClusterRole, always on a conflict and otherwise only with --v=3
+func (o *EditDeployOptions) printManagedFields(entries []metav1.ManagedFieldsEntry, conflict bool) {
+	if !o.showManagedFields || (!conflict && !o.log.V(3).Enabled()) {
+		return
+	}
+	fmt.Fprintf(o.ErrOut, "managedFields of clusterrole %s:\n", o.clusterRoleName)
+	if err := managedfields.Print(o.ErrOut, entries, o.log.V(3).Enabled()); err != nil {
+		fmt.Fprintf(o.ErrOut, "cannot print managedFields: %v\n", err)
+	}
+}
+
+//Function to fetch the current managedFields after a conflict so the competing managers are visible
+func (o *EditDeployOptions) printManagedFieldsOnConflict(err error) {
+	if !o.showManagedFields || !apierrors.IsConflict(err) {
+		return
+	}
+	current, getErr := o.clusterRoleInterface.Get(context.TODO(), o.clusterRoleName, metav1.GetOptions{})
+	if getErr != nil {
+		fmt.Fprintf(o.ErrOut, "cannot get managedFields: %v\n", getErr)
+		return
+	}
+	o.printManagedFields(current.ManagedFields, true)
+}
+
+//Function to print the diff of the ClusterRole as fetched and as returned by the update, --show-diff
+func (o *EditDeployOptions) printChanges(before, after *v1.ClusterRole) {
+	if !o.showChanges || before == nil || after == nil {
+		return
+	}
+	if err := diff.PrintChanged(o.Out, before, after, o.clusterRoleName); err != nil {
+		fmt.Fprintf(o.ErrOut, "Warning: cannot diff clusterrole: %v\n", err)
+	}
+}
+
 func main() {
 	flags := flag.NewFlagSet("kubectl-edit_cr", flag.ExitOnError)
 	flag.CommandLine = flags
 
 	root := NewCmdEdit(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
 	if err := root.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(exitcode.For(err))
 	}
 }
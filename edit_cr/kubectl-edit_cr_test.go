@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"common/protection"
+
+	v1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+//Options and fake clientset of one command run, with the output it wrote
+type testRun struct {
+	o         *EditDeployOptions
+	clientset *fake.Clientset
+	in        *bytes.Buffer
+	out       *bytes.Buffer
+	errOut    *bytes.Buffer
+}
+
+//Function to return a run against a fake clientset holding objects
+//The config file of the environment is cleared so tests do not depend on the machine
+func newTestRun(t *testing.T, objects ...runtime.Object) *testRun {
+	t.Helper()
+	t.Setenv(protection.EnvVar, filepath.Join(t.TempDir(), "edit-plugins.yaml"))
+
+	streams, in, out, errOut := genericclioptions.NewTestIOStreams()
+	return &testRun{
+		o:         NewEditDeploymentOptions(streams),
+		clientset: fake.NewSimpleClientset(objects...),
+		in:        in,
+		out:       out,
+		errOut:    errOut,
+	}
+}
+
+//Function to parse args like the command line and complete the options against the fake clientset
+func (r *testRun) complete(args ...string) error {
+	cmd := newCmdEdit(r.o)
+	if err := cmd.ParseFlags(args); err != nil {
+		return err
+	}
+	if err := r.o.completeFlags(cmd.Flags().Args()); err != nil {
+		return err
+	}
+	r.o.completeClient(r.clientset)
+	return nil
+}
+
+//Function to go through Complete, Validate and Run like the command does
+func (r *testRun) run(args ...string) error {
+	if err := r.complete(args...); err != nil {
+		return err
+	}
+	if err := r.o.Validate(); err != nil {
+		return err
+	}
+	return r.o.Run()
+}
+
+//Function to point the config file at one protecting the namespaces
+func protectNamespaces(t *testing.T, namespaces ...string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "edit-plugins.yaml")
+	content := "protectedNamespaces: [" + strings.Join(namespaces, ", ") + "]\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(protection.EnvVar, path)
+}
+
+//ClusterRole reader allowing get and list of pods
+func testClusterRole() *v1.ClusterRole {
+	return &v1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "reader"},
+		Rules: []v1.PolicyRule{
+			{Verbs: []string{"get", "list"}, APIGroups: []string{""}, Resources: []string{"pods"}},
+		},
+	}
+}
+
+func TestLevelZeroOutput(t *testing.T) {
+	r := newTestRun(t, testClusterRole())
+	if err := r.run("reader", "--verbs=get", "--resources=deployments", "--groups=apps"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	if got, want := r.out.String(), "Updated ClusterRoles..\n"; got != want {
+		t.Errorf("out = %q, want %q", got, want)
+	}
+	if got := r.errOut.String(); got != "" {
+		t.Errorf("errOut = %q, want nothing at level 0", got)
+	}
+}
+
+func TestVerboseOnlyWritesErrOut(t *testing.T) {
+	r := newTestRun(t, testClusterRole())
+	if err := r.run("reader", "--verbs=get", "--resources=deployments", "--groups=apps", "-v=2"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	if got, want := r.out.String(), "Updated ClusterRoles..\n"; got != want {
+		t.Errorf("out = %q, want %q", got, want)
+	}
+	for _, want := range []string{"target clusterrole reader", "GET clusterrole reader", "PUT clusterrole reader"} {
+		if !bytes.Contains(r.errOut.Bytes(), []byte(want)) {
+			t.Errorf("errOut misses %q:\n%s", want, r.errOut.String())
+		}
+	}
+}
+
+func TestPruneRules(t *testing.T) {
+	clusterRole := testClusterRole()
+	clusterRole.Rules = append(clusterRole.Rules, v1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"secrets"}})
+
+	r := newTestRun(t, clusterRole)
+	if err := r.run("reader", "--verbs=get,list", "--resources=deployments", "--groups=apps", "--prune-rules"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	want := []v1.PolicyRule{{Verbs: []string{"get", "list"}, APIGroups: []string{"apps"}, Resources: []string{"deployments"}}}
+	if got := storedRules(t, r); !reflect.DeepEqual(got, want) {
+		t.Errorf("rules = %v, want %v", got, want)
+	}
+	if got, want := r.out.String(), "Updated ClusterRoles.. 2 rules removed, 1 added\n"; got != want {
+		t.Errorf("out = %q, want %q", got, want)
+	}
+}
+
+func TestPruneRulesValidation(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--resources=deployments", "--prune-rules"}, "--prune-rules needs --verbs and --resources, removing every rule without a replacement would grant no permissions"},
+		{[]string{"--verbs=get", "--prune-rules"}, "--prune-rules needs --verbs and --resources, removing every rule without a replacement would grant no permissions"},
+		{[]string{"--aggregate-selector=team=web", "--prune-rules"}, "--prune-rules cannot be combined with --aggregate-selector"},
+	}
+	for _, tt := range tests {
+		r := newTestRun(t, testClusterRole())
+		err := r.run(append([]string{"reader"}, tt.args...)...)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("run(%q) = %v, want %q", tt.args, err, tt.want)
+		}
+		if got := storedRules(t, r); !reflect.DeepEqual(got, testClusterRole().Rules) {
+			t.Errorf("run(%q) changed the rules to %v", tt.args, got)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"common/apicheck"
+	"common/apiserver"
+	"common/exitcode"
+	"common/table"
+	"common/vlog"
+
+	v1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
+	typev1 "k8s.io/client-go/kubernetes/typed/rbac/v1"
+)
+
+//Global variable to define usage of list-cr
+var (
+	listExample = `
+	# list every ClusterRole with its number of rules
+	%[1]s edit-cr list-cr
+
+	# print the rules of one ClusterRole
+	%[1]s edit-cr list-cr <clusterResourceName>
+
+	# -o = print the ClusterRole as json or yaml instead
+	%[1]s edit-cr list-cr <clusterResourceName> -o yaml
+
+	# --apiserver = try these API server endpoints in order and use the first one responding
+	%[1]s edit-cr list-cr --apiserver=https://10.0.0.1:6443 --apiserver=https://10.0.0.2:6443
+
+	`
+)
+
+//Struct having all the flags arguments variable of list-cr
+type ListCROptions struct {
+	configFlags *genericclioptions.ConfigFlags
+
+	clusterRoleInterface typev1.ClusterRoleInterface
+	discoveryClient      discovery.DiscoveryInterface
+	clusterRoleName      string
+
+	//"", "wide", "json" or "yaml"
+	output string
+
+	verbosity int
+	log       *vlog.Logger
+
+	//Endpoints of --apiserver, the first one responding is used
+	apiservers []string
+
+	args []string
+
+	genericclioptions.IOStreams
+}
+
+//Function to return struct object with default value of flags
+func NewListCROptions(streams genericclioptions.IOStreams) *ListCROptions {
+	return &ListCROptions{
+		configFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+//Read-only subcommand of edit-cr printing the rules of a ClusterRole
+func NewCmdListCR(streams genericclioptions.IOStreams) *cobra.Command {
+	return newCmdListCR(NewListCROptions(streams))
+}
+
+//Command bound to the given options, tests parse flags into their own options
+func newCmdListCR(o *ListCROptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "list-cr [ClusterRoleName] [flags]",
+		Short:        "List ClusterRoles or the rules of one ClusterRole",
+		Long:         "List ClusterRoles or the rules of one ClusterRole\n\n" + exitcode.Help,
+		Example:      fmt.Sprintf(listExample, "kubectl"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			return o.Run()
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.output, "output", "o", "", "Output format, one of \"wide\", \"json\" or \"yaml\"")
+	vlog.AddFlags(cmd.Flags(), &o.verbosity)
+	apiserver.AddFlags(cmd.Flags(), &o.apiservers)
+	o.configFlags.AddFlags(cmd.Flags())
+	return cmd
+}
+
+//Function to store all flags and arguments in struct
+func (o *ListCROptions) Complete(cmd *cobra.Command, args []string) error {
+	o.completeFlags(args)
+
+	config, err := o.configFlags.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+
+	if config, err = apiserver.Resolve(config, *o.configFlags.APIServer, o.apiservers, o.log); err != nil {
+		return err
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	o.completeClient(clientset)
+	return nil
+}
+
+//Function to store the arguments and set up the logger
+func (o *ListCROptions) completeFlags(args []string) {
+	o.args = args
+	o.log = vlog.New(o.ErrOut, o.verbosity)
+
+	if len(args) > 0 {
+		o.clusterRoleName = args[0]
+	}
+}
+
+//Function to set the clients of the ClusterRoles
+func (o *ListCROptions) completeClient(clientset kubernetes.Interface) {
+	o.clusterRoleInterface = clientset.RbacV1().ClusterRoles()
+	o.discoveryClient = clientset.Discovery()
+}
+
+//Function to validate if the arguments and flags are correct
+func (o *ListCROptions) Validate() error {
+	if len(o.args) > 1 {
+		return fmt.Errorf("at most one ClusterRole name is allowed")
+	}
+
+	switch o.output {
+	case "", "wide", "json", "yaml":
+	default:
+		return fmt.Errorf("invalid --output %q, must be one of \"wide\", \"json\" or \"yaml\"", o.output)
+	}
+	return nil
+}
+
+//Function to print the named ClusterRole, or every ClusterRole when no name is given
+func (o *ListCROptions) Run() error {
+	if len(o.clusterRoleName) == 0 {
+		return o.listAll()
+	}
+
+	start := time.Now()
+	clusterRole, err := o.clusterRoleInterface.Get(context.TODO(), o.clusterRoleName, metav1.GetOptions{})
+	o.log.V(2).Infof("GET clusterrole %s (%v)", o.clusterRoleName, time.Since(start))
+	if err != nil {
+		return fmt.Errorf("failed to get ClusterRole: %w", apicheck.Unavailable(o.discoveryClient, v1.SchemeGroupVersion, err))
+	}
+
+	if o.output == "json" || o.output == "yaml" {
+		//Objects returned by the typed client have no kind and apiVersion
+		clusterRole.SetGroupVersionKind(v1.SchemeGroupVersion.WithKind("ClusterRole"))
+		return o.printObject(clusterRole)
+	}
+
+	if len(clusterRole.Rules) == 0 {
+		fmt.Fprintf(o.Out, "ClusterRole %s has no rules\n", o.clusterRoleName)
+		return nil
+	}
+
+	t := table.New()
+	t.AddColumn("api groups")
+	t.AddColumn("resources", table.MaxWidth(60))
+	t.AddColumn("resource names", table.MaxWidth(40))
+	t.AddColumn("verbs")
+	t.AddColumn("non resource urls")
+	for _, rule := range clusterRole.Rules {
+		t.AddRow(joinOrNone(rule.APIGroups), joinOrNone(rule.Resources), joinOrNone(rule.ResourceNames), joinOrNone(rule.Verbs), joinOrNone(rule.NonResourceURLs))
+	}
+	return t.Render(o.Out, o.output == "wide")
+}
+
+//Function to print the name and rule count of every ClusterRole
+func (o *ListCROptions) listAll() error {
+	start := time.Now()
+	clusterRoles, err := o.clusterRoleInterface.List(context.TODO(), metav1.ListOptions{})
+	o.log.V(2).Infof("LIST clusterroles (%v)", time.Since(start))
+	if err != nil {
+		return fmt.Errorf("failed to list ClusterRoles: %w", apicheck.Unavailable(o.discoveryClient, v1.SchemeGroupVersion, err))
+	}
+
+	if o.output == "json" || o.output == "yaml" {
+		clusterRoles.SetGroupVersionKind(v1.SchemeGroupVersion.WithKind("ClusterRoleList"))
+		for i := range clusterRoles.Items {
+			clusterRoles.Items[i].SetGroupVersionKind(v1.SchemeGroupVersion.WithKind("ClusterRole"))
+		}
+		return o.printObject(clusterRoles)
+	}
+
+	t := table.New()
+	t.AddColumn("name")
+	t.AddColumn("rules")
+	t.AddColumn("aggregated", table.WideOnly())
+	for _, clusterRole := range clusterRoles.Items {
+		t.AddRow(clusterRole.Name, strconv.Itoa(len(clusterRole.Rules)), strconv.FormatBool(clusterRole.AggregationRule != nil))
+	}
+	return t.Render(o.Out, o.output == "wide")
+}
+
+func (o *ListCROptions) printObject(object runtime.Object) error {
+	var data []byte
+	var err error
+	if o.output == "json" {
+		data, err = json.MarshalIndent(object, "", "    ")
+		data = append(data, '\n')
+	} else {
+		data, err = yaml.Marshal(object)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = o.Out.Write(data)
+	return err
+}
+
+//Empty lists are printed as <none> like kubectl does
+func joinOrNone(values []string) string {
+	if len(values) == 0 {
+		return "<none>"
+	}
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"common/exitcode"
+
+	v1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+//Run go test . -update to rewrite the golden files after an intended change
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+//ClusterRoles of the list-cr tests: reader, one aggregated and one without rules
+func listCRObjects() []runtime.Object {
+	admin := &v1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "admin"},
+		AggregationRule: &v1.AggregationRule{
+			ClusterRoleSelectors: []metav1.LabelSelector{{MatchLabels: map[string]string{"rbac.example.com/aggregate-to-admin": "true"}}},
+		},
+		Rules: []v1.PolicyRule{
+			{Verbs: []string{"*"}, APIGroups: []string{"apps"}, Resources: []string{"deployments", "deployments/scale", "replicasets", "statefulsets", "daemonsets", "controllerrevisions"}},
+			{Verbs: []string{"get", "update"}, APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"sizing", "feature-flags"}},
+			{Verbs: []string{"get"}, NonResourceURLs: []string{"/healthz", "/version"}},
+		},
+	}
+	empty := &v1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "empty"}}
+	return []runtime.Object{testClusterRole(), admin, empty}
+}
+
+//Function to run list-cr with args against a fake clientset holding the test ClusterRoles
+func runListCR(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	o := NewListCROptions(streams)
+	cmd := newCmdListCR(o)
+	if err := cmd.ParseFlags(args); err != nil {
+		return "", err
+	}
+	o.completeFlags(cmd.Flags().Args())
+	o.completeClient(fake.NewSimpleClientset(listCRObjects()...))
+	if err := o.Validate(); err != nil {
+		return "", err
+	}
+	err := o.Run()
+	return out.String(), err
+}
+
+func TestListCR(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		golden string
+	}{
+		{"names", nil, "list.golden"},
+		{"names wide", []string{"-o", "wide"}, "list-wide.golden"},
+		{"names yaml", []string{"-o", "yaml"}, "list-yaml.golden"},
+		{"rules", []string{"admin"}, "rules.golden"},
+		{"rules wide", []string{"admin", "-o", "wide"}, "rules-wide.golden"},
+		{"rules json", []string{"admin", "-o", "json"}, "rules-json.golden"},
+		{"rules yaml", []string{"admin", "-o", "yaml"}, "rules-yaml.golden"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := runListCR(t, tt.args...)
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			assertGolden(t, tt.golden, []byte(out))
+		})
+	}
+}
+
+func TestListCRNoRules(t *testing.T) {
+	out, err := runListCR(t, "empty")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if want := "ClusterRole empty has no rules\n"; out != want {
+		t.Errorf("out = %q, want %q", out, want)
+	}
+}
+
+func TestListCRErrors(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"missing"}, `failed to get ClusterRole: clusterroles.rbac.authorization.k8s.io "missing" not found`},
+		{[]string{"reader", "admin"}, "at most one ClusterRole name is allowed"},
+		{[]string{"-o", "table"}, `invalid --output "table", must be one of "wide", "json" or "yaml"`},
+	}
+	for _, tt := range tests {
+		if _, err := runListCR(t, tt.args...); err == nil || err.Error() != tt.want {
+			t.Errorf("run(%v) = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestListCRHelpDocumentsExitCodes(t *testing.T) {
+	streams, _, _, _ := genericclioptions.NewTestIOStreams()
+	if cmd := NewCmdListCR(streams); !strings.HasSuffix(cmd.Long, exitcode.Help) {
+		t.Errorf("Long = %q, want it to end with the exit codes", cmd.Long)
+	}
+}
+
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("cannot write golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("cannot read golden file: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"common/protection"
+)
+
+//Function to stop replacing the rules of a ClusterRole granted in the protectedNamespaces of the config file
+//A ClusterRole is not namespaced, it counts as protected when a RoleBinding in a protected namespace
+//or any ClusterRoleBinding, which grants it in every namespace, references it
+func (o *EditDeployOptions) checkProtection() (bool, error) {
+	replace := o.pruneRules || (o.pruneUnused && o.applyPrune)
+	if !replace || o.protection == nil || len(o.protection.ProtectedNamespaces) == 0 {
+		return true, nil
+	}
+
+	grants, err := o.protectedGrants()
+	if err != nil {
+		return false, err
+	}
+	if len(grants) == 0 {
+		return true, nil
+	}
+
+	fmt.Fprintf(o.Out, "ClusterRole %s is protected, it is granted by %s\n", o.clusterRoleName, strings.Join(grants, ", "))
+	fmt.Fprintln(o.Out, "  replace rules")
+
+	target := fmt.Sprintf("protected ClusterRole %q", o.clusterRoleName)
+	proceed, err := protection.Confirm(o.answers, o.Out, target, o.overrideProtection, []string{"replace rules"})
+	if err != nil {
+		return false, err
+	}
+	if !proceed {
+		fmt.Fprintln(o.Out, "Edit cancelled..")
+	}
+	return proceed, nil
+}
+
+//Bindings of the ClusterRole that reach a protected namespace
+func (o *EditDeployOptions) protectedGrants() ([]string, error) {
+	var grants []string
+
+	start := time.Now()
+	clusterRoleBindings, err := o.rbacClient.ClusterRoleBindings().List(context.TODO(), metav1.ListOptions{})
+	o.log.V(2).Infof("LIST clusterrolebindings (%v)", time.Since(start))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ClusterRoleBindings: %w", err)
+	}
+	for _, binding := range clusterRoleBindings.Items {
+		if binding.RoleRef.Kind == "ClusterRole" && binding.RoleRef.Name == o.clusterRoleName {
+			grants = append(grants, "ClusterRoleBinding "+binding.Name)
+		}
+	}
+
+	for _, namespace := range o.protection.ProtectedNamespaces {
+		start := time.Now()
+		roleBindings, err := o.rbacClient.RoleBindings(namespace).List(context.TODO(), metav1.ListOptions{})
+		o.log.V(2).Infof("LIST rolebindings %s (%v)", namespace, time.Since(start))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list RoleBindings in %s: %w", namespace, err)
+		}
+		for _, binding := range roleBindings.Items {
+			if binding.RoleRef.Kind == "ClusterRole" && binding.RoleRef.Name == o.clusterRoleName {
+				grants = append(grants, fmt.Sprintf("RoleBinding %s/%s", namespace, binding.Name))
+			}
+		}
+	}
+	return grants, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var readerRef = v1.RoleRef{APIGroup: v1.GroupName, Kind: "ClusterRole", Name: "reader"}
+
+func storedRules(t *testing.T, r *testRun) []v1.PolicyRule {
+	t.Helper()
+	stored, err := r.clientset.RbacV1().ClusterRoles().Get(context.TODO(), "reader", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stored.Rules
+}
+
+func TestProtectedClusterRole(t *testing.T) {
+	tests := []struct {
+		name    string
+		binding runtime.Object
+		grant   string
+	}{
+		{
+			name:    "ClusterRoleBinding",
+			binding: &v1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "reader-all"}, RoleRef: readerRef},
+			grant:   "ClusterRoleBinding reader-all",
+		},
+		{
+			name:    "RoleBinding in protected namespace",
+			binding: &v1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "reader", Namespace: "kube-system"}, RoleRef: readerRef},
+			grant:   "RoleBinding kube-system/reader",
+		},
+	}
+	args := []string{"reader", "--verbs=get", "--resources=deployments", "--groups=apps", "--prune-rules"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//Refused without --override-protection
+			r := newTestRun(t, testClusterRole(), tt.binding)
+			protectNamespaces(t, "kube-system")
+			err := r.run(args...)
+			if want := `protected ClusterRole "reader": replace rules needs --override-protection`; err == nil || err.Error() != want {
+				t.Fatalf("run = %v, want %q", err, want)
+			}
+			if want := "ClusterRole reader is protected, it is granted by " + tt.grant + "\n  replace rules\n"; r.out.String() != want {
+				t.Errorf("out = %q, want %q", r.out.String(), want)
+			}
+			if rules := storedRules(t, r); len(rules) != 1 || rules[0].Resources[0] != "pods" {
+				t.Errorf("rules changed without --override-protection: %+v", rules)
+			}
+
+			//Asked with --override-protection, declining changes nothing
+			r = newTestRun(t, testClusterRole(), tt.binding)
+			protectNamespaces(t, "kube-system")
+			r.in.WriteString("n\n")
+			if err := r.run(append(args, "--override-protection")...); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if prompt := `Run replace rules in protected ClusterRole "reader"? [y/N]: `; !strings.Contains(r.out.String(), prompt) {
+				t.Errorf("out misses %q:\n%s", prompt, r.out.String())
+			}
+			if !strings.Contains(r.out.String(), "Edit cancelled..") {
+				t.Errorf("out misses the cancel:\n%s", r.out.String())
+			}
+			if rules := storedRules(t, r); rules[0].Resources[0] != "pods" {
+				t.Errorf("declined edit was applied: %+v", rules)
+			}
+
+			//Accepting replaces the rules
+			r = newTestRun(t, testClusterRole(), tt.binding)
+			protectNamespaces(t, "kube-system")
+			r.in.WriteString("y\n")
+			if err := r.run(append(args, "--override-protection")...); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if !strings.Contains(r.out.String(), "Updated ClusterRoles.. 1 rules removed, 1 added\n") {
+				t.Errorf("out misses the update:\n%s", r.out.String())
+			}
+			if rules := storedRules(t, r); len(rules) != 1 || rules[0].Resources[0] != "deployments" {
+				t.Errorf("rules = %+v, want only the deployments rule", rules)
+			}
+		})
+	}
+}
+
+func TestProtectionAllowsSafeEdits(t *testing.T) {
+	clusterRoleBinding := &v1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "reader-all"}, RoleRef: readerRef}
+	tests := []struct {
+		name    string
+		binding runtime.Object
+		args    []string
+	}{
+		{
+			name:    "append rule",
+			binding: clusterRoleBinding,
+			args:    []string{"--verbs=get", "--resources=deployments", "--groups=apps"},
+		},
+		{
+			name:    "RoleBinding in other namespace",
+			binding: &v1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "reader", Namespace: "team"}, RoleRef: readerRef},
+			args:    []string{"--verbs=get", "--resources=deployments", "--groups=apps", "--prune-rules"},
+		},
+		{
+			name:    "binding of other ClusterRole",
+			binding: &v1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "admin"}, RoleRef: v1.RoleRef{APIGroup: v1.GroupName, Kind: "ClusterRole", Name: "admin"}},
+			args:    []string{"--verbs=get", "--resources=deployments", "--groups=apps", "--prune-rules"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRun(t, testClusterRole(), tt.binding)
+			protectNamespaces(t, "kube-system")
+			if err := r.run(append([]string{"reader"}, tt.args...)...); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if strings.Contains(r.out.String(), "is protected") {
+				t.Errorf("safe edit was treated as protected:\n%s", r.out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"common/apicheck"
+	"common/table"
+	"common/vlog"
+
+	v1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+//Audit lines can carry whole request and response objects
+const maxAuditLineBytes = 16 * 1024 * 1024
+
+//The fields of an audit.k8s.io/v1 Event needed to tell which rule a request used
+type auditEvent struct {
+	Verb       string `json:"verb"`
+	RequestURI string `json:"requestURI"`
+	User       struct {
+		Username string   `json:"username"`
+		Groups   []string `json:"groups"`
+	} `json:"user"`
+	ObjectRef *struct {
+		Resource    string `json:"resource"`
+		Subresource string `json:"subresource"`
+		APIGroup    string `json:"apiGroup"`
+		Name        string `json:"name"`
+	} `json:"objectRef"`
+	ResponseStatus *struct {
+		Code int `json:"code"`
+	} `json:"responseStatus"`
+}
+
+//Users and groups bound to the ClusterRole, only their requests count as use of its rules
+type roleSubjects struct {
+	users  map[string]bool
+	groups map[string]bool
+}
+
+func (s roleSubjects) empty() bool {
+	return len(s.users) == 0 && len(s.groups) == 0
+}
+
+func (s roleSubjects) match(event auditEvent) bool {
+	if s.users[event.User.Username] {
+		return true
+	}
+	for _, group := range event.User.Groups {
+		if s.groups[group] {
+			return true
+		}
+	}
+	return false
+}
+
+//Function to validate --prune-unused, --audit-file, --apply and --allow-empty
+func (o *EditDeployOptions) validatePruneUnused() error {
+	if !o.pruneUnused {
+		if len(o.auditFile) > 0 || o.applyPrune || o.allowEmpty || len(o.output) > 0 {
+			return fmt.Errorf("--audit-file, --apply, --allow-empty and --output only apply to --prune-unused")
+		}
+		return nil
+	}
+	if o.allowEmpty && !o.applyPrune {
+		return fmt.Errorf("--allow-empty only applies to --apply")
+	}
+	switch o.output {
+	case "", "wide", "json":
+	default:
+		return fmt.Errorf("invalid --output %q, must be \"wide\" or \"json\"", o.output)
+	}
+	if len(o.auditFile) == 0 {
+		return fmt.Errorf("--prune-unused needs --audit-file")
+	}
+	if len(o.newVerbs) > 0 || len(o.newResources) > 0 || len(o.newApiGroups) > 0 || len(o.aggregateSelector) > 0 || o.pruneRules {
+		return fmt.Errorf("--prune-unused cannot be combined with --verbs, --resources, --groups, --aggregate-selector or --prune-rules")
+	}
+	if o.serverSide {
+		return fmt.Errorf("--prune-unused does not support --server-side")
+	}
+	return nil
+}
+
+//Function to find the rules no request in the audit log used and remove them with --apply
+//Audit events do not say which role allowed a request, so requests of the subjects bound to the ClusterRole are matched against its rules
+func (o *EditDeployOptions) runPruneUnused() error {
+	subjects, err := o.boundSubjects()
+	if err != nil {
+		return err
+	}
+	if subjects.empty() {
+		fmt.Fprintf(o.ErrOut, "Warning: no bindings reference ClusterRole %q, every rule counts as unused\n", o.clusterRoleName)
+	}
+
+	events, err := readAuditEvents(o.auditFile, subjects)
+	if err != nil {
+		return err
+	}
+	o.log.V(1).Infof("read %d allowed requests of subjects bound to clusterrole %s from %s", len(events), o.clusterRoleName, o.auditFile)
+
+	start := time.Now()
+	live, getErr := o.clusterRoleInterface.Get(context.TODO(), o.clusterRoleName, metav1.GetOptions{})
+	o.log.V(2).Infof("GET clusterrole %s (%v)", o.clusterRoleName, time.Since(start))
+	if getErr != nil {
+		return fmt.Errorf("failed to get ClusterRole: %w", apicheck.Unavailable(o.discoveryClient, v1.SchemeGroupVersion, getErr))
+	}
+
+	unused := 0
+	t := table.New()
+	t.AddColumn("verbs")
+	t.AddColumn("api groups")
+	t.AddColumn("resources", table.MaxWidth(60))
+	t.AddColumn("status")
+	for _, rule := range live.Rules {
+		usage := "used"
+		if !ruleUsed(rule, events) {
+			usage = "unused"
+			unused++
+		}
+		t.AddRow(strings.Join(rule.Verbs, ","), strings.Join(rule.APIGroups, ","), ruleTargets(rule), usage)
+	}
+	//With json only the report goes to Out so it stays parsable, the status lines go to ErrOut
+	status := o.Out
+	if o.output == "json" {
+		status = o.ErrOut
+		if err := t.RenderJSON(o.Out); err != nil {
+			return err
+		}
+	} else if err := t.Render(o.Out, o.output == "wide" || o.log.V(1).Enabled()); err != nil {
+		return err
+	}
+
+	if unused == 0 {
+		fmt.Fprintln(status, "No unused rules..")
+		return nil
+	}
+	if !o.applyPrune {
+		fmt.Fprintf(status, "%d of %d rules unused (dry run), pass --apply to remove them\n", unused, len(live.Rules))
+		return nil
+	}
+	//Without bound subjects nothing in the audit log can count as use, so every rule would go
+	if subjects.empty() && !o.allowEmpty {
+		return fmt.Errorf("no bindings reference ClusterRole %q, refusing to remove its rules without --allow-empty", o.clusterRoleName)
+	}
+	if err := o.checkNotEmptied(len(live.Rules), unused); err != nil {
+		return err
+	}
+
+	//The ClusterRole may have changed since it was printed, usage is decided again for the rules of every attempt
+	removed := 0
+	var before, updated *v1.ClusterRole
+	retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		result, getErr := o.clusterRoleInterface.Get(context.TODO(), o.clusterRoleName, metav1.GetOptions{})
+		if getErr != nil {
+			return fmt.Errorf("failed to get latest version fo ClusterRole: %w", getErr)
+		}
+
+		before = result.DeepCopy()
+		var kept []v1.PolicyRule
+		for _, rule := range result.Rules {
+			if ruleUsed(rule, events) {
+				kept = append(kept, rule)
+			}
+		}
+		removed = len(result.Rules) - len(kept)
+		if err := o.checkNotEmptied(len(result.Rules), removed); err != nil {
+			return err
+		}
+		result.Rules = kept
+
+		start := time.Now()
+		var updateErr error
+		updated, updateErr = o.clusterRoleInterface.Update(context.TODO(), result, metav1.UpdateOptions{})
+		o.log.V(2).Infof("PUT clusterrole %s (%v): %v", o.clusterRoleName, time.Since(start), vlog.ErrOrOK(updateErr))
+		return updateErr
+	})
+	if retryErr != nil {
+		return fmt.Errorf("update failed: %w", apicheck.Unavailable(o.discoveryClient, v1.SchemeGroupVersion, retryErr))
+	}
+
+	fmt.Fprintf(status, "Updated ClusterRoles.. %d unused rules removed\n", removed)
+	o.printChanges(before, updated)
+	return nil
+}
+
+//Function to refuse removing every rule, an audit log missing the traffic of the subjects looks the same
+func (o *EditDeployOptions) checkNotEmptied(rules, removed int) error {
+	if o.allowEmpty || rules == 0 || removed < rules {
+		return nil
+	}
+	return fmt.Errorf("every rule of ClusterRole %q is unused, refusing to remove all %d rules without --allow-empty; check that --audit-file covers the requests of the bound subjects", o.clusterRoleName, rules)
+}
+
+//Function to collect the users and groups of every ClusterRoleBinding and RoleBinding referencing the ClusterRole
+func (o *EditDeployOptions) boundSubjects() (roleSubjects, error) {
+	subjects := roleSubjects{users: map[string]bool{}, groups: map[string]bool{}}
+
+	start := time.Now()
+	clusterRoleBindings, err := o.rbacClient.ClusterRoleBindings().List(context.TODO(), metav1.ListOptions{})
+	o.log.V(2).Infof("LIST clusterrolebindings (%v)", time.Since(start))
+	if err != nil {
+		return subjects, fmt.Errorf("failed to list ClusterRoleBindings: %w", err)
+	}
+	start = time.Now()
+	roleBindings, err := o.rbacClient.RoleBindings(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	o.log.V(2).Infof("LIST rolebindings in all namespaces (%v)", time.Since(start))
+	if err != nil {
+		return subjects, fmt.Errorf("failed to list RoleBindings: %w", err)
+	}
+
+	add := func(roleRef v1.RoleRef, bindingSubjects []v1.Subject) {
+		if roleRef.Kind != "ClusterRole" || roleRef.Name != o.clusterRoleName {
+			return
+		}
+		for _, subject := range bindingSubjects {
+			switch subject.Kind {
+			case v1.UserKind:
+				subjects.users[subject.Name] = true
+			case v1.GroupKind:
+				subjects.groups[subject.Name] = true
+			case v1.ServiceAccountKind:
+				subjects.users[fmt.Sprintf("system:serviceaccount:%s:%s", subject.Namespace, subject.Name)] = true
+			}
+		}
+	}
+	for _, binding := range clusterRoleBindings.Items {
+		add(binding.RoleRef, binding.Subjects)
+	}
+	for _, binding := range roleBindings.Items {
+		add(binding.RoleRef, binding.Subjects)
+	}
+	return subjects, nil
+}
+
+//Function to read the allowed requests of the subjects from a json lines audit log
+func readAuditEvents(path string, subjects roleSubjects) ([]auditEvent, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read --audit-file: %w", err)
+	}
+	defer file.Close()
+
+	var events []auditEvent
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxAuditLineBytes)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var event auditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("invalid audit event on line %d of %s: %w", line, path, err)
+		}
+		//Denied requests did not use any rule
+		if event.ResponseStatus != nil && event.ResponseStatus.Code == http.StatusForbidden {
+			continue
+		}
+		if subjects.match(event) {
+			events = append(events, event)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read --audit-file: %w", err)
+	}
+	return events, nil
+}
+
+//Function to tell whether any of the requests was allowed by the rule
+func ruleUsed(rule v1.PolicyRule, events []auditEvent) bool {
+	for _, event := range events {
+		if ruleAllows(rule, event) {
+			return true
+		}
+	}
+	return false
+}
+
+//Same matching as the RBAC authorizer, including "*" and subresources
+func ruleAllows(rule v1.PolicyRule, event auditEvent) bool {
+	if !matches(rule.Verbs, event.Verb) {
+		return false
+	}
+
+	if event.ObjectRef == nil {
+		path := strings.SplitN(event.RequestURI, "?", 2)[0]
+		for _, url := range rule.NonResourceURLs {
+			if url == "*" || url == path || (strings.HasSuffix(url, "*") && strings.HasPrefix(path, strings.TrimSuffix(url, "*"))) {
+				return true
+			}
+		}
+		return false
+	}
+
+	ref := event.ObjectRef
+	if !matches(rule.APIGroups, ref.APIGroup) {
+		return false
+	}
+	resource := ref.Resource
+	if len(ref.Subresource) > 0 {
+		resource = ref.Resource + "/" + ref.Subresource
+	}
+	if !matches(rule.Resources, resource) && !(len(ref.Subresource) > 0 && matches(rule.Resources, "*/"+ref.Subresource)) {
+		return false
+	}
+	return len(rule.ResourceNames) == 0 || contains(rule.ResourceNames, ref.Name)
+}
+
+//The list contains the value or "*"
+func matches(list []string, value string) bool {
+	return contains(list, v1.VerbAll) || contains(list, value)
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+//Resources of the rule or its non resource URLs
+func ruleTargets(rule v1.PolicyRule) string {
+	if len(rule.Resources) > 0 {
+		return strings.Join(rule.Resources, ",")
+	}
+	return strings.Join(rule.NonResourceURLs, ",")
+}
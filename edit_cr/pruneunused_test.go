@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+//Audit log of the reader ServiceAccount in namespace team and of other users
+const testAuditLog = `{"verb":"get","requestURI":"/api/v1/namespaces/team/pods/web-1","user":{"username":"system:serviceaccount:team:reader"},"objectRef":{"resource":"pods","name":"web-1"},"responseStatus":{"code":200}}
+
+{"verb":"delete","requestURI":"/api/v1/namespaces/team/pods/web-1","user":{"username":"system:serviceaccount:team:reader"},"objectRef":{"resource":"pods","name":"web-1"},"responseStatus":{"code":403}}
+{"verb":"list","requestURI":"/apis/apps/v1/deployments","user":{"username":"alice","groups":["auditors"]},"objectRef":{"resource":"deployments","apiGroup":"apps"},"responseStatus":{"code":200}}
+{"verb":"get","requestURI":"/healthz","user":{"username":"bob"},"responseStatus":{"code":200}}
+`
+
+func writeAuditLog(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.log")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func resourceEvent(verb, group, resource, subresource, name string) auditEvent {
+	var event auditEvent
+	event.Verb = verb
+	event.ObjectRef = &struct {
+		Resource    string `json:"resource"`
+		Subresource string `json:"subresource"`
+		APIGroup    string `json:"apiGroup"`
+		Name        string `json:"name"`
+	}{Resource: resource, Subresource: subresource, APIGroup: group, Name: name}
+	return event
+}
+
+func urlEvent(verb, uri string) auditEvent {
+	var event auditEvent
+	event.Verb = verb
+	event.RequestURI = uri
+	return event
+}
+
+func TestRuleAllows(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  v1.PolicyRule
+		event auditEvent
+		want  bool
+	}{
+		{"exact", v1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}}, resourceEvent("get", "", "pods", "", "web-1"), true},
+		{"other verb", v1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}}, resourceEvent("delete", "", "pods", "", "web-1"), false},
+		{"other group", v1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"deployments"}}, resourceEvent("get", "apps", "deployments", "", ""), false},
+		{"wildcards", v1.PolicyRule{Verbs: []string{"*"}, APIGroups: []string{"*"}, Resources: []string{"*"}}, resourceEvent("patch", "apps", "deployments", "", "web"), true},
+		{"subresource", v1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods/log"}}, resourceEvent("get", "", "pods", "log", "web-1"), true},
+		{"resource without subresource", v1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}}, resourceEvent("get", "", "pods", "log", "web-1"), false},
+		{"any resource of subresource", v1.PolicyRule{Verbs: []string{"update"}, APIGroups: []string{"apps"}, Resources: []string{"*/scale"}}, resourceEvent("update", "apps", "deployments", "scale", "web"), true},
+		{"resource name", v1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}, ResourceNames: []string{"web-1"}}, resourceEvent("get", "", "pods", "", "web-1"), true},
+		{"other resource name", v1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}, ResourceNames: []string{"web-2"}}, resourceEvent("get", "", "pods", "", "web-1"), false},
+		{"non resource url", v1.PolicyRule{Verbs: []string{"get"}, NonResourceURLs: []string{"/healthz"}}, urlEvent("get", "/healthz?verbose"), true},
+		{"non resource url prefix", v1.PolicyRule{Verbs: []string{"get"}, NonResourceURLs: []string{"/metrics*"}}, urlEvent("get", "/metrics/cadvisor"), true},
+		{"other non resource url", v1.PolicyRule{Verbs: []string{"get"}, NonResourceURLs: []string{"/healthz"}}, urlEvent("get", "/version"), false},
+		{"resource rule for url", v1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{"*"}, Resources: []string{"*"}}, urlEvent("get", "/healthz"), false},
+	}
+	for _, tt := range tests {
+		if got := ruleAllows(tt.rule, tt.event); got != tt.want {
+			t.Errorf("%s: ruleAllows = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadAuditEvents(t *testing.T) {
+	path := writeAuditLog(t, testAuditLog)
+	subjects := roleSubjects{
+		users:  map[string]bool{"system:serviceaccount:team:reader": true},
+		groups: map[string]bool{"auditors": true},
+	}
+
+	events, err := readAuditEvents(path, subjects)
+	if err != nil {
+		t.Fatalf("readAuditEvents: %v", err)
+	}
+	//The forbidden delete and the request of bob do not count
+	var got []string
+	for _, event := range events {
+		got = append(got, event.Verb+" "+event.User.Username)
+	}
+	if want := []string{"get system:serviceaccount:team:reader", "list alice"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestReadAuditEventsInvalidLine(t *testing.T) {
+	path := writeAuditLog(t, testAuditLog+"not json\n")
+	_, err := readAuditEvents(path, roleSubjects{})
+	if err == nil || !strings.Contains(err.Error(), "invalid audit event on line 6") {
+		t.Errorf("readAuditEvents = %v, want an error for line 6", err)
+	}
+}
+
+func TestReadAuditEventsMissingFile(t *testing.T) {
+	_, err := readAuditEvents(filepath.Join(t.TempDir(), "missing.log"), roleSubjects{})
+	if err == nil || !strings.Contains(err.Error(), "cannot read --audit-file") {
+		t.Errorf("readAuditEvents = %v, want a read error", err)
+	}
+}
+
+//ClusterRole reader bound to the reader ServiceAccount of namespace team
+func boundClusterRole(rules ...v1.PolicyRule) []runtime.Object {
+	clusterRole := testClusterRole()
+	clusterRole.Rules = rules
+	binding := &v1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "reader"},
+		RoleRef:    v1.RoleRef{APIGroup: v1.GroupName, Kind: "ClusterRole", Name: "reader"},
+		Subjects:   []v1.Subject{{Kind: v1.ServiceAccountKind, Name: "reader", Namespace: "team"}},
+	}
+	return []runtime.Object{clusterRole, binding}
+}
+
+func TestPruneUnusedApply(t *testing.T) {
+	used := v1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}}
+	unused := v1.PolicyRule{Verbs: []string{"delete"}, APIGroups: []string{""}, Resources: []string{"secrets"}}
+	r := newTestRun(t, boundClusterRole(used, unused)...)
+
+	if err := r.run("reader", "--prune-unused", "--audit-file="+writeAuditLog(t, testAuditLog), "--apply"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if !strings.Contains(r.out.String(), "Updated ClusterRoles.. 1 unused rules removed") {
+		t.Errorf("out misses the update:\n%s", r.out.String())
+	}
+	stored, err := r.clientset.RbacV1().ClusterRoles().Get(context.TODO(), "reader", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Rules) != 1 || stored.Rules[0].Verbs[0] != "get" {
+		t.Errorf("stored rules = %v, want only the used rule", stored.Rules)
+	}
+}
+
+func TestPruneUnusedRefusesEmpty(t *testing.T) {
+	unused := v1.PolicyRule{Verbs: []string{"delete"}, APIGroups: []string{""}, Resources: []string{"secrets"}}
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		want    string
+	}{
+		{"every rule unused", boundClusterRole(unused), `every rule of ClusterRole "reader" is unused, refusing to remove all 1 rules without --allow-empty`},
+		{"no bindings", []runtime.Object{testClusterRole()}, `no bindings reference ClusterRole "reader", refusing to remove its rules without --allow-empty`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRun(t, tt.objects...)
+			err := r.run("reader", "--prune-unused", "--audit-file="+writeAuditLog(t, testAuditLog), "--apply")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("run = %v, want %q", err, tt.want)
+			}
+			for _, action := range r.clientset.Actions() {
+				if action.GetVerb() == "update" {
+					t.Errorf("ClusterRole was updated despite the refusal")
+				}
+			}
+
+			//--allow-empty removes them anyway
+			r = newTestRun(t, tt.objects...)
+			if err := r.run("reader", "--prune-unused", "--audit-file="+writeAuditLog(t, testAuditLog), "--apply", "--allow-empty"); err != nil {
+				t.Fatalf("run with --allow-empty: %v", err)
+			}
+			stored, err := r.clientset.RbacV1().ClusterRoles().Get(context.TODO(), "reader", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(stored.Rules) != 0 {
+				t.Errorf("stored rules = %v, want none with --allow-empty", stored.Rules)
+			}
+		})
+	}
+}
+
+func TestAllowEmptyNeedsApply(t *testing.T) {
+	r := newTestRun(t, testClusterRole())
+	err := r.run("reader", "--prune-unused", "--audit-file="+writeAuditLog(t, testAuditLog), "--allow-empty")
+	if err == nil || err.Error() != "--allow-empty only applies to --apply" {
+		t.Errorf("run = %v, want the --allow-empty error", err)
+	}
+}
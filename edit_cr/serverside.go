@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"common/apicheck"
+	"common/diff"
+	"common/vlog"
+
+	v1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	rbacv1ac "k8s.io/client-go/applyconfigurations/rbac/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+//Function to append the rule with server-side apply
+//The rules of a ClusterRole are an atomic list, so the live rules are sent together with the new one
+//otherwise applying would drop every rule not owned by our field manager
+//The apply carries the resourceVersion of the rules it was built from, a ClusterRole changed in between
+//is fetched again instead of having the rules added since then overwritten
+func (o *EditDeployOptions) runServerSide() error {
+	attempt := 0
+	var live, applied *v1.ClusterRole
+	retryErr := retry.OnError(retry.DefaultRetry, resourceVersionConflict, func() error {
+		attempt++
+		o.log.V(2).Infof("apply attempt %d", attempt)
+
+		start := time.Now()
+		var getErr error
+		live, getErr = o.clusterRoleInterface.Get(context.TODO(), o.clusterRoleName, metav1.GetOptions{})
+		o.log.V(2).Infof("GET clusterrole %s (%v)", o.clusterRoleName, time.Since(start))
+		if getErr != nil {
+			return fmt.Errorf("failed to get ClusterRole: %w", apicheck.Unavailable(o.discoveryClient, v1.SchemeGroupVersion, getErr))
+		}
+
+		o.log.V(1).Infof("fetched clusterrole %s resourceVersion %s with %d rules", o.clusterRoleName, live.ResourceVersion, len(live.Rules))
+
+		newRule := o.newRule()
+		o.log.V(1).Infof("computed change: apply rule verbs=%v resources=%v apiGroups=%v", newRule.Verbs, newRule.Resources, newRule.APIGroups)
+
+		//--prune-rules applies only the new rule, which replaces the whole atomic list
+		keep := live.Rules
+		if o.pruneRules {
+			o.log.V(1).Infof("computed change: remove %d rules", len(live.Rules))
+			keep = nil
+		}
+		rules := make([]*rbacv1ac.PolicyRuleApplyConfiguration, 0, len(keep)+1)
+		for _, rule := range append(keep, newRule) {
+			rules = append(rules, policyRuleApplyConfiguration(rule))
+		}
+		clusterRole := rbacv1ac.ClusterRole(o.clusterRoleName).
+			WithResourceVersion(live.ResourceVersion).
+			WithRules(rules...)
+
+		start = time.Now()
+		var applyErr error
+		applied, applyErr = o.clusterRoleInterface.Apply(context.TODO(), clusterRole, metav1.ApplyOptions{FieldManager: o.fieldManager, Force: o.forceConflicts})
+		o.log.V(2).Infof("PATCH (apply) clusterrole %s as %q (%v): %v", o.clusterRoleName, o.fieldManager, time.Since(start), vlog.ErrOrOK(applyErr))
+		return applyErr
+	})
+	if retryErr != nil {
+		o.printManagedFieldsOnConflict(retryErr)
+		return fmt.Errorf("server-side apply failed: %w", retryErr)
+	}
+	diff.Log(o.log, live, applied)
+	o.printManagedFields(applied.ManagedFields, false)
+
+	if o.pruneRules {
+		fmt.Fprintf(o.Out, "Updated ClusterRoles.. (server-side) %d rules removed, 1 added\n", len(live.Rules))
+	} else {
+		fmt.Fprintln(o.Out, "Updated ClusterRoles.. (server-side)")
+	}
+	o.printChanges(live, applied)
+	return nil
+}
+
+//A stale resourceVersion is retried, a conflict with another field manager needs --force-conflicts and is not
+func resourceVersionConflict(err error) bool {
+	if !apierrors.IsConflict(err) {
+		return false
+	}
+	var status apierrors.APIStatus
+	if errors.As(err, &status) && status.Status().Details != nil {
+		for _, cause := range status.Status().Details.Causes {
+			if cause.Type == metav1.CauseTypeFieldManagerConflict {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func policyRuleApplyConfiguration(rule v1.PolicyRule) *rbacv1ac.PolicyRuleApplyConfiguration {
+	return rbacv1ac.PolicyRule().
+		WithVerbs(rule.Verbs...).
+		WithAPIGroups(rule.APIGroups...).
+		WithResources(rule.Resources...).
+		WithResourceNames(rule.ResourceNames...).
+		WithNonResourceURLs(rule.NonResourceURLs...)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+//Message of the conflict returned for a stale resourceVersion
+var errObjectModified = errors.New("the object has been modified; please apply your changes to the latest version and try again")
+
+//Function to answer server-side applies of clusterroles with the patch itself, the fake tracker cannot apply
+//conflicts are returned for the first applies, sent collects every applied object
+func applyReactor(t *testing.T, r *testRun, conflicts []error, sent *[]v1.ClusterRole) {
+	r.clientset.PrependReactor("patch", "clusterroles", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		var applied v1.ClusterRole
+		if err := json.Unmarshal(action.(k8stesting.PatchAction).GetPatch(), &applied); err != nil {
+			t.Fatalf("apply patch is not a ClusterRole: %v", err)
+		}
+		*sent = append(*sent, applied)
+		if len(*sent) <= len(conflicts) {
+			//Someone else changed the ClusterRole in between
+			current := testClusterRole()
+			current.ResourceVersion = "2"
+			if err := r.clientset.Tracker().Update(v1.SchemeGroupVersion.WithResource("clusterroles"), current, ""); err != nil {
+				t.Fatalf("update tracker: %v", err)
+			}
+			return true, nil, conflicts[len(*sent)-1]
+		}
+		return true, &applied, nil
+	})
+}
+
+func TestServerSideRetriesStaleResourceVersion(t *testing.T) {
+	live := testClusterRole()
+	live.ResourceVersion = "1"
+	r := newTestRun(t, live)
+	var sent []v1.ClusterRole
+	stale := apierrors.NewConflict(v1.Resource("clusterroles"), "reader", errObjectModified)
+	applyReactor(t, r, []error{stale}, &sent)
+
+	if err := r.run("reader", "--server-side", "--verbs=get", "--resources=deployments", "--groups=apps"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	if len(sent) != 2 {
+		t.Fatalf("got %d applies, want 2", len(sent))
+	}
+	for i, want := range []string{"1", "2"} {
+		if got := sent[i].ResourceVersion; got != want {
+			t.Errorf("apply %d resourceVersion = %q, want %q", i+1, got, want)
+		}
+	}
+	if got := len(sent[1].Rules); got != 2 {
+		t.Errorf("retried apply has %d rules, want the live rule and the new one", got)
+	}
+	if got, want := r.out.String(), "Updated ClusterRoles.. (server-side)\n"; got != want {
+		t.Errorf("out = %q, want %q", got, want)
+	}
+}
+
+func TestServerSideDoesNotRetryFieldManagerConflict(t *testing.T) {
+	live := testClusterRole()
+	live.ResourceVersion = "1"
+	r := newTestRun(t, live)
+	var sent []v1.ClusterRole
+	owned := apierrors.NewApplyConflict([]metav1.StatusCause{{
+		Type:    metav1.CauseTypeFieldManagerConflict,
+		Message: `conflict with "rbac-operator"`,
+		Field:   ".rules",
+	}}, `Apply failed with 1 conflict: conflict with "rbac-operator": .rules`)
+	applyReactor(t, r, []error{owned}, &sent)
+
+	err := r.run("reader", "--server-side", "--verbs=get", "--resources=deployments", "--groups=apps")
+	if err == nil || !strings.Contains(err.Error(), "rbac-operator") {
+		t.Fatalf("run = %v, want the field manager conflict", err)
+	}
+	if len(sent) != 1 {
+		t.Errorf("got %d applies, want 1 without retry", len(sent))
+	}
+}
+
+func TestResourceVersionConflict(t *testing.T) {
+	stale := apierrors.NewConflict(v1.Resource("clusterroles"), "reader", errObjectModified)
+	owned := apierrors.NewApplyConflict([]metav1.StatusCause{{Type: metav1.CauseTypeFieldManagerConflict}}, "conflict")
+	notFound := apierrors.NewNotFound(v1.Resource("clusterroles"), "reader")
+
+	if !resourceVersionConflict(stale) {
+		t.Error("stale resourceVersion is not retried")
+	}
+	if resourceVersionConflict(owned) {
+		t.Error("field manager conflict is retried")
+	}
+	if resourceVersionConflict(notFound) {
+		t.Error("NotFound is retried")
+	}
+}
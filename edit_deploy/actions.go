@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+//Annotation set by the freeze action and removed by thaw
+const frozenAnnotation = "edit-deploy/frozen"
+
+//A preset of --action, expand sets the option fields from the live deployment
+//and returns the plan lines printed before anything is applied
+type action struct {
+	name        string
+	description string
+	//Flags the action sets itself, passing them explicitly as well is rejected
+	flags  []string
+	expand func(o *EditDeployOptions, live *appsv1.Deployment) []string
+}
+
+//Add new presets here, each one only combines mutations the command already supports
+var actions = []action{
+	{
+		name:        "emergency-scale-up",
+		description: "double the replicas, apply without asking and wait for the rollout",
+		flags:       []string{"replicas", "wait", "yes"},
+		expand: func(o *EditDeployOptions, live *appsv1.Deployment) []string {
+			current := replicasOf(live)
+			o.newReplicas = current * 2
+			if o.newReplicas == 0 {
+				o.newReplicas = 1
+			}
+			o.yes = true
+			fmt.Fprintf(o.ErrOut, "EMERGENCY SCALE UP of deployment %s/%s: replicas %d -> %d\n", o.namespace, o.deploymentName, current, o.newReplicas)
+			plan := []string{
+				fmt.Sprintf("replicas %d -> %d", current, o.newReplicas),
+				"apply without confirmation",
+			}
+			//A dry run starts no rollout, so there is nothing to wait for
+			if o.dryRun == dryRunNone {
+				o.wait = true
+				plan = append(plan, "wait for the rollout")
+			}
+			return plan
+		},
+	},
+	{
+		name:        "freeze",
+		description: "pause rollouts and mark the deployment frozen",
+		flags:       []string{"annotation"},
+		expand: func(o *EditDeployOptions, live *appsv1.Deployment) []string {
+			paused := true
+			o.paused = &paused
+			o.annotationChanges.set[frozenAnnotation] = "true"
+			return []string{
+				fmt.Sprintf("paused %t -> true", live.Spec.Paused),
+				fmt.Sprintf("annotation %s=true", frozenAnnotation),
+			}
+		},
+	},
+	{
+		name:        "thaw",
+		description: "resume rollouts and remove the frozen mark",
+		flags:       []string{"annotation"},
+		expand: func(o *EditDeployOptions, live *appsv1.Deployment) []string {
+			paused := false
+			o.paused = &paused
+			o.annotationChanges.remove = append(o.annotationChanges.remove, frozenAnnotation)
+			return []string{
+				fmt.Sprintf("paused %t -> false", live.Spec.Paused),
+				fmt.Sprintf("remove annotation %s", frozenAnnotation),
+			}
+		},
+	},
+	{
+		name:        "standby",
+		description: "scale to zero replicas",
+		flags:       []string{"replicas"},
+		expand: func(o *EditDeployOptions, live *appsv1.Deployment) []string {
+			o.newReplicas = 0
+			return []string{
+				fmt.Sprintf("replicas %d -> 0", replicasOf(live)),
+			}
+		},
+	},
+}
+
+//Function to find the preset of --action
+func lookupAction(name string) (action, error) {
+	for _, a := range actions {
+		if a.name == name {
+			return a, nil
+		}
+	}
+	return action{}, fmt.Errorf("unknown --action %q, must be one of: %s", name, strings.Join(actionNames(), ", "))
+}
+
+func actionNames() []string {
+	names := make([]string, 0, len(actions))
+	for _, a := range actions {
+		names = append(names, a.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//Usage text of --action listing every preset
+func actionUsage() string {
+	lines := []string{"Preset to run:"}
+	for _, a := range actions {
+		lines = append(lines, fmt.Sprintf("%s = %s", a.name, a.description))
+	}
+	return strings.Join(lines, "\n")
+}
+
+//Function to expand --action, must run after the live deployment is fetched
+func (o *EditDeployOptions) expandAction() error {
+	if len(o.action) == 0 {
+		return nil
+	}
+
+	a, err := lookupAction(o.action)
+	if err != nil {
+		return err
+	}
+	for _, flag := range a.flags {
+		if o.changedFlags[flag] {
+			return fmt.Errorf("--action=%s sets --%s itself, drop --%s", a.name, flag, flag)
+		}
+	}
+
+	o.actionPlan = a.expand(o, o.live)
+	return nil
+}
+
+//Function to print what the action is going to change
+func (o *EditDeployOptions) printActionPlan() {
+	if len(o.actionPlan) == 0 {
+		return
+	}
+	fmt.Fprintf(o.Out, "Action %s on deployment %s/%s:\n", o.action, o.namespace, o.deploymentName)
+	for _, line := range o.actionPlan {
+		fmt.Fprintf(o.Out, "  %s\n", line)
+	}
+}
+
+func replicasOf(deployment *appsv1.Deployment) int32 {
+	if deployment.Spec.Replicas == nil {
+		return 1
+	}
+	return *deployment.Spec.Replicas
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestActions(t *testing.T) {
+	tests := []struct {
+		action       string
+		replicas     int32
+		paused       bool
+		wantReplicas int32
+		wantYes      bool
+		wantWait     bool
+		wantPaused   *bool
+		wantSet      map[string]string
+		wantRemove   []string
+		wantPlan     []string
+	}{
+		{
+			action: "emergency-scale-up", replicas: 3,
+			wantReplicas: 6, wantYes: true, wantWait: true,
+			wantSet:  map[string]string{},
+			wantPlan: []string{"replicas 3 -> 6", "apply without confirmation", "wait for the rollout"},
+		},
+		{
+			action: "emergency-scale-up", replicas: 0,
+			wantReplicas: 1, wantYes: true, wantWait: true,
+			wantSet:  map[string]string{},
+			wantPlan: []string{"replicas 0 -> 1", "apply without confirmation", "wait for the rollout"},
+		},
+		{
+			action: "freeze", replicas: 3,
+			wantReplicas: 3, wantPaused: boolPtr(true),
+			wantSet:  map[string]string{frozenAnnotation: "true"},
+			wantPlan: []string{"paused false -> true", "annotation edit-deploy/frozen=true"},
+		},
+		{
+			action: "thaw", replicas: 3, paused: true,
+			wantReplicas: 3, wantPaused: boolPtr(false),
+			wantSet:    map[string]string{},
+			wantRemove: []string{frozenAnnotation},
+			wantPlan:   []string{"paused true -> false", "remove annotation edit-deploy/frozen"},
+		},
+		{
+			action: "standby", replicas: 3,
+			wantReplicas: 0,
+			wantSet:      map[string]string{},
+			wantPlan:     []string{"replicas 3 -> 0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			deployment := testDeployment()
+			deployment.Spec.Replicas = &tt.replicas
+			deployment.Spec.Paused = tt.paused
+			r := newTestRun(t, deployment)
+
+			if err := r.complete("web", "-n", "team", "--action="+tt.action); err != nil {
+				t.Fatalf("complete: %v", err)
+			}
+			o := r.o
+			if o.newReplicas != tt.wantReplicas {
+				t.Errorf("replicas = %d, want %d", o.newReplicas, tt.wantReplicas)
+			}
+			if o.yes != tt.wantYes || o.wait != tt.wantWait {
+				t.Errorf("yes, wait = %t, %t, want %t, %t", o.yes, o.wait, tt.wantYes, tt.wantWait)
+			}
+			if !reflect.DeepEqual(o.paused, tt.wantPaused) {
+				t.Errorf("paused = %v, want %v", o.paused, tt.wantPaused)
+			}
+			if !reflect.DeepEqual(o.annotationChanges.set, tt.wantSet) || !reflect.DeepEqual(o.annotationChanges.remove, tt.wantRemove) {
+				t.Errorf("annotation changes = %+v, want set %v remove %v", o.annotationChanges, tt.wantSet, tt.wantRemove)
+			}
+			if !reflect.DeepEqual(o.actionPlan, tt.wantPlan) {
+				t.Errorf("plan = %q, want %q", o.actionPlan, tt.wantPlan)
+			}
+		})
+	}
+}
+
+//The flags an action sets itself cannot be passed as well
+func TestActionRejectsOwnFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--action=emergency-scale-up", "--replicas=10"}, "--action=emergency-scale-up sets --replicas itself, drop --replicas"},
+		{[]string{"--action=emergency-scale-up", "--wait"}, "--action=emergency-scale-up sets --wait itself, drop --wait"},
+		{[]string{"--action=emergency-scale-up", "--yes"}, "--action=emergency-scale-up sets --yes itself, drop --yes"},
+		{[]string{"--action=freeze", "--annotation=owner=platform"}, "--action=freeze sets --annotation itself, drop --annotation"},
+		{[]string{"--action=thaw", "--annotation=owner-"}, "--action=thaw sets --annotation itself, drop --annotation"},
+		{[]string{"--action=standby", "--replicas=0"}, "--action=standby sets --replicas itself, drop --replicas"},
+		{[]string{"--action=reboot"}, "unknown --action \"reboot\", must be one of: emergency-scale-up, freeze, standby, thaw"},
+	}
+	for _, tt := range tests {
+		r := newTestRun(t, testDeployment())
+		err := r.complete(append([]string{"web", "-n", "team"}, tt.args...)...)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("complete(%v) = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestActionPlanPrinted(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	if err := r.run("web", "-n", "team", "--action=standby"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	want := "Action standby on deployment team/web:\n  replicas 3 -> 0\nUpdated Deployment.. replicas=0, revisionHistoryLimit=10\n"
+	if got := r.out.String(); got != want {
+		t.Errorf("out = %q, want %q", got, want)
+	}
+	stored, err := r.clientset.AppsV1().Deployments("team").Get(context.TODO(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replicasOf(stored) != 0 {
+		t.Errorf("stored replicas = %d, want 0", replicasOf(stored))
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+//A dry run previews the emergency scale-up without the implied --wait
+func TestEmergencyScaleUpDryRun(t *testing.T) {
+	for _, dryRun := range []string{"--dry-run", "--dry-run=server"} {
+		r := newTestRun(t, testDeployment())
+		if err := r.run("web", "-n", "team", "--action=emergency-scale-up", dryRun); err != nil {
+			t.Fatalf("run(%s): %v", dryRun, err)
+		}
+		if r.o.wait {
+			t.Errorf("%s: wait = true, want no wait on a dry run", dryRun)
+		}
+		want := []string{"replicas 3 -> 6", "apply without confirmation"}
+		if !reflect.DeepEqual(r.o.actionPlan, want) {
+			t.Errorf("%s: plan = %q, want %q", dryRun, r.o.actionPlan, want)
+		}
+		if strings.Contains(r.out.String(), "wait for the rollout") {
+			t.Errorf("%s: out announces a wait:\n%s", dryRun, r.out.String())
+		}
+	}
+}
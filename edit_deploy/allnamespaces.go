@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"common/apicheck"
+	"common/vlog"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+//Function to validate the flags of --all-namespaces
+func (o *EditDeployOptions) validateAllNamespaces() error {
+	if len(o.namespaceSelector) > 0 {
+		if !o.allNamespaces {
+			return fmt.Errorf("--namespace-selector only applies to --all-namespaces")
+		}
+		if _, err := labels.Parse(o.namespaceSelector); err != nil {
+			return fmt.Errorf("invalid --namespace-selector %q: %w", o.namespaceSelector, err)
+		}
+	}
+
+	if !o.allNamespaces {
+		return nil
+	}
+
+	if len(o.service) > 0 {
+		return fmt.Errorf("--service cannot be combined with --all-namespaces")
+	}
+
+	//Namespaces run in parallel, there is no way to ask before each protected one
+	if o.overrideProtection {
+		return fmt.Errorf("--override-protection cannot be combined with --all-namespaces, edit protected namespaces one at a time")
+	}
+
+	//Every namespace has its own deployment and therefore its own resourceVersion
+	if len(o.expectedResourceVersion) > 0 {
+		return fmt.Errorf("--resource-version cannot be combined with --all-namespaces")
+	}
+
+	if o.configFlags.Namespace != nil && len(*o.configFlags.Namespace) > 0 {
+		return fmt.Errorf("--namespace cannot be combined with --all-namespaces")
+	}
+	if o.concurrency < 1 {
+		return fmt.Errorf("invalid --concurrency %d, must be at least 1", o.concurrency)
+	}
+	//Namespaces run in parallel, so there is no way to answer one prompt per namespace
+	if o.showDiff && !o.yes && o.dryRun == dryRunNone {
+		return fmt.Errorf("--diff with --all-namespaces needs --yes or --dry-run, there is no confirmation prompt per namespace")
+	}
+	return nil
+}
+
+//Function to edit the deployment in every namespace matching --namespace-selector
+//Namespaces without the deployment are skipped, failures are collected into one error
+func (o *EditDeployOptions) runAllNamespaces() error {
+	start := time.Now()
+	namespaces, err := o.clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{LabelSelector: o.namespaceSelector})
+	o.log.V(2).Infof("LIST namespaces %q (%v)", o.namespaceSelector, time.Since(start))
+	if err != nil {
+		return fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	var (
+		mu               sync.Mutex
+		wg               sync.WaitGroup
+		updated, skipped int
+		errs             []error
+		//A group/version the cluster does not serve fails every namespace the same way
+		unavailable    error
+		namespaceNames = make(chan string)
+	)
+
+	for i := 0; i < o.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for namespace := range namespaceNames {
+				mu.Lock()
+				stop := unavailable != nil
+				mu.Unlock()
+				if stop {
+					continue
+				}
+
+				var out, errOut bytes.Buffer
+				wasSkipped, editErr := o.editNamespace(namespace, &out, &errOut)
+
+				mu.Lock()
+				if apicheck.IsUnavailable(editErr) {
+					if unavailable == nil {
+						unavailable = editErr
+					}
+					mu.Unlock()
+					continue
+				}
+				writePrefixed(o.Out, namespace, &out)
+				writePrefixed(o.ErrOut, namespace, &errOut)
+				switch {
+				case editErr != nil:
+					errs = append(errs, fmt.Errorf("namespace %s: %w", namespace, editErr))
+				case wasSkipped:
+					skipped++
+				default:
+					updated++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, namespace := range namespaces.Items {
+		namespaceNames <- namespace.Name
+	}
+	close(namespaceNames)
+	wg.Wait()
+
+	if unavailable != nil {
+		return unavailable
+	}
+
+	fmt.Fprintf(o.Out, "Deployment %q: %d updated, %d skipped, %d failed\n", o.deploymentName, updated, skipped, len(errs))
+	//A single failure keeps its own error, so its exit code and message are those of a single namespace edit
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+//Function to edit the deployment in one namespace, reports true when the namespace has no such deployment
+func (o *EditDeployOptions) editNamespace(namespace string, out, errOut io.Writer) (bool, error) {
+	target := o.forNamespace(namespace, out, errOut)
+
+	if err := target.loadTarget(); err != nil {
+		//Only a missing deployment skips the namespace, e.g. a missing ConfigMap is a failure
+		//and a missing apps/v1 API fails the whole command
+		if target.live == nil && apierrors.IsNotFound(err) && !apicheck.IsUnavailable(err) {
+			fmt.Fprintf(out, "deployment %q not found, skipping\n", o.deploymentName)
+			return true, nil
+		}
+		return false, err
+	}
+
+	//Checks depending on the live object could not run before it was fetched
+	if err := target.Validate(); err != nil {
+		return false, err
+	}
+	return false, target.edit()
+}
+
+//Function to copy the options for one namespace with its own client and output
+func (o *EditDeployOptions) forNamespace(namespace string, out, errOut io.Writer) *EditDeployOptions {
+	target := *o
+	target.allNamespaces = false
+	target.namespaceSelector = ""
+	target.namespace = namespace
+	target.deploymentsClient = o.clientset.AppsV1().Deployments(namespace)
+	//Namespaces run in parallel and never ask, a question would read an empty answer, which is no,
+	//instead of racing the other namespaces for the shared input
+	target.IOStreams = genericclioptions.IOStreams{In: strings.NewReader(""), Out: out, ErrOut: errOut}
+	target.answers = bufio.NewReader(target.In)
+	target.changedFlags = make(map[string]bool, len(o.changedFlags))
+	for name, changed := range o.changedFlags {
+		target.changedFlags[name] = changed
+	}
+	target.log = vlog.New(errOut, o.verbosity)
+	target.live = nil
+	target.actionPlan = nil
+	target.paused = nil
+	//Actions add to the changes, every namespace needs its own maps
+	target.labelChanges = o.labelChanges.copy()
+	target.annotationChanges = o.annotationChanges.copy()
+	return &target
+}
+
+//Prefix every line with the namespace so parallel output stays readable
+func writePrefixed(w io.Writer, namespace string, buf *bytes.Buffer) {
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		fmt.Fprintf(w, "%s: %s\n", namespace, scanner.Text())
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"common/apicheck"
+	"common/exitcode"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+//Function to return a run with deployment web in every namespace, updates in the forbidden ones are denied
+func newAllNamespacesRun(t *testing.T, namespaces []string, forbidden ...string) *testRun {
+	var objects []runtime.Object
+	for _, namespace := range namespaces {
+		deployment := testDeployment()
+		deployment.Namespace = namespace
+		objects = append(objects, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}, deployment)
+	}
+	r := newTestRun(t, objects...)
+	r.clientset.PrependReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		for _, namespace := range forbidden {
+			if action.GetNamespace() == namespace {
+				return true, nil, apierrors.NewForbidden(appsv1.Resource("deployments"), "web", errors.New("denied"))
+			}
+		}
+		return false, nil, nil
+	})
+	return r
+}
+
+//A single failed namespace is returned as is, not as an aggregate of one
+func TestAllNamespacesSingleFailure(t *testing.T) {
+	r := newAllNamespacesRun(t, []string{"team", "shop"}, "shop")
+
+	err := r.run("web", "-A", "--replicas=5")
+	if err == nil || !strings.HasPrefix(err.Error(), "namespace shop: ") {
+		t.Fatalf("run = %v, want the error of namespace shop", err)
+	}
+	var aggregate utilerrors.Aggregate
+	if errors.As(err, &aggregate) {
+		t.Errorf("run returned an aggregate for one failure: %v", err)
+	}
+	if code := exitcode.For(err); code != exitcode.Forbidden {
+		t.Errorf("exit code = %d, want %d", code, exitcode.Forbidden)
+	}
+	if !strings.Contains(r.out.String(), `Deployment "web": 1 updated, 0 skipped, 1 failed`) {
+		t.Errorf("out misses the summary:\n%s", r.out.String())
+	}
+}
+
+func TestAllNamespacesSeveralFailures(t *testing.T) {
+	r := newAllNamespacesRun(t, []string{"team", "shop", "docs"}, "shop", "docs")
+
+	err := r.run("web", "-A", "--replicas=5")
+	var aggregate utilerrors.Aggregate
+	if !errors.As(err, &aggregate) || len(aggregate.Errors()) != 2 {
+		t.Fatalf("run = %v, want an aggregate of two failures", err)
+	}
+	if code := exitcode.For(err); code != exitcode.Forbidden {
+		t.Errorf("exit code = %d, want %d shared by every failure", code, exitcode.Forbidden)
+	}
+	if !strings.Contains(r.out.String(), `Deployment "web": 1 updated, 0 skipped, 2 failed`) {
+		t.Errorf("out misses the summary:\n%s", r.out.String())
+	}
+}
+
+//Namespaces without the deployment are skipped with a note, they are not failures
+func TestAllNamespacesSkipsMissingDeployment(t *testing.T) {
+	deployment := testDeployment()
+	r := newTestRun(t,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team"}}, deployment,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop"}},
+	)
+	if err := r.run("web", "-A", "--replicas=5"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	out := r.out.String()
+	for _, want := range []string{
+		"shop: deployment \"web\" not found, skipping\n",
+		"team: Updated Deployment.. replicas=5, revisionHistoryLimit=10\n",
+		"Deployment \"web\": 1 updated, 1 skipped, 0 failed\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("out misses %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestAllNamespacesSelector(t *testing.T) {
+	var objects []runtime.Object
+	for namespace, env := range map[string]string{"team": "prod", "shop": "prod", "lab": "dev"} {
+		deployment := testDeployment()
+		deployment.Namespace = namespace
+		objects = append(objects, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace, Labels: map[string]string{"env": env}}}, deployment)
+	}
+	r := newTestRun(t, objects...)
+
+	if err := r.run("web", "-A", "--namespace-selector=env=prod", "--replicas=5"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	for namespace, want := range map[string]int32{"team": 5, "shop": 5, "lab": 3} {
+		stored, err := r.clientset.AppsV1().Deployments(namespace).Get(context.TODO(), "web", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if replicasOf(stored) != want {
+			t.Errorf("%s: replicas = %d, want %d", namespace, replicasOf(stored), want)
+		}
+	}
+	if !strings.Contains(r.out.String(), "Deployment \"web\": 2 updated, 0 skipped, 0 failed\n") {
+		t.Errorf("out misses the summary:\n%s", r.out.String())
+	}
+}
+
+func TestAllNamespacesValidation(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"web", "-A", "--concurrency=0"}, "invalid --concurrency 0, must be at least 1"},
+		{[]string{"web", "-A", "--namespace-selector=env in (prod"}, `invalid --namespace-selector "env in (prod": `},
+		{[]string{"web", "-n", "team", "--namespace-selector=env=prod"}, "--namespace-selector only applies to --all-namespaces"},
+		{[]string{"web", "-A", "--override-protection"}, "--override-protection cannot be combined with --all-namespaces, edit protected namespaces one at a time"},
+	}
+	for _, tt := range tests {
+		r := newAllNamespacesRun(t, []string{"team"})
+		err := r.run(tt.args...)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("run(%v) = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+}
+
+//At most --concurrency namespaces are edited at the same time
+func TestAllNamespacesConcurrency(t *testing.T) {
+	var namespaces []string
+	for i := 0; i < 6; i++ {
+		namespaces = append(namespaces, fmt.Sprintf("team-%d", i))
+	}
+
+	for _, concurrency := range []int{1, 2} {
+		r := newAllNamespacesRun(t, namespaces)
+		var mu sync.Mutex
+		active, most, calls := 0, 0, 0
+		r.o.runHook = func(command string, env []string, out, errOut io.Writer) error {
+			mu.Lock()
+			active++
+			calls++
+			if active > most {
+				most = active
+			}
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			active--
+			mu.Unlock()
+			return nil
+		}
+
+		if err := r.run("web", "-A", "--replicas=5", "--pre-hook=true", fmt.Sprintf("--concurrency=%d", concurrency)); err != nil {
+			t.Fatalf("run: %v", err)
+		}
+		if calls != len(namespaces) {
+			t.Errorf("--concurrency=%d: edited %d namespaces, want %d", concurrency, calls, len(namespaces))
+		}
+		if most > concurrency {
+			t.Errorf("--concurrency=%d: %d namespaces were edited at once", concurrency, most)
+		}
+	}
+}
+
+//A cluster without apps/v1 is one error, not a skip in every namespace
+func TestAllNamespacesAPIUnavailable(t *testing.T) {
+	r := newAllNamespacesRun(t, []string{"team", "shop"})
+	r.clientset.PrependReactor("get", "deployments", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewGenericServerResponse(404, "get", appsv1.Resource("deployments"), "web", "", 0, true)
+	})
+	discovery := r.clientset.Discovery().(*fakediscovery.FakeDiscovery)
+	discovery.Resources = []*metav1.APIResourceList{{GroupVersion: "v1"}}
+
+	err := r.run("web", "-A", "--replicas=5")
+	if !apicheck.IsUnavailable(err) {
+		t.Fatalf("run = %v, want the unavailable apps/v1 API", err)
+	}
+	var aggregate utilerrors.Aggregate
+	if errors.As(err, &aggregate) {
+		t.Errorf("run returned an aggregate: %v", err)
+	}
+	if strings.Contains(r.out.String(), "skipping") {
+		t.Errorf("namespaces were skipped instead of failing:\n%s", r.out.String())
+	}
+}
+
+//Every namespace gets its own input and flag state, workers share nothing they could write
+func TestForNamespaceCopiesState(t *testing.T) {
+	r := newAllNamespacesRun(t, []string{"team"})
+	if err := r.complete("web", "-A", "--replicas=5"); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	target := r.o.forNamespace("team", io.Discard, io.Discard)
+
+	if target.answers == r.o.answers || target.In == r.o.In {
+		t.Error("target shares the input of the command")
+	}
+	target.changedFlags["rhl"] = true
+	if r.o.changedFlags["rhl"] {
+		t.Error("target shares changedFlags with the command")
+	}
+	if !target.changedFlags["replicas"] {
+		t.Error("target lost the changed flags of the command")
+	}
+}
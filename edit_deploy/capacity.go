@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//CPU in millicores and memory in bytes
+type capacity struct {
+	cpu    int64
+	memory int64
+}
+
+//Function to warn when a replica increase cannot fit the allocatable capacity of the schedulable nodes
+//The check is advisory, only --strict turns the warning into an error
+func (o *EditDeployOptions) checkCapacity() error {
+	current := replicasOf(o.live)
+	if !o.capacityCheck || o.newReplicas <= current {
+		return nil
+	}
+
+	perPod := podRequests(&o.live.Spec.Template.Spec)
+	if perPod.cpu == 0 && perPod.memory == 0 {
+		o.log.V(1).Infof("pod template of deployment %q has no resource requests, skipping capacity check", o.deploymentName)
+		return nil
+	}
+
+	start := time.Now()
+	nodes, err := o.clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	o.log.V(2).Infof("LIST nodes (%v)", time.Since(start))
+	if err != nil {
+		return fmt.Errorf("capacity check failed to list nodes: %w", err)
+	}
+	allocatable := schedulableCapacity(nodes.Items)
+
+	//Other workloads use part of the allocatable capacity too, so exceeding the total means the pods will stay Pending
+	requested := capacity{cpu: perPod.cpu * int64(o.newReplicas), memory: perPod.memory * int64(o.newReplicas)}
+	if requested.cpu <= allocatable.cpu && requested.memory <= allocatable.memory {
+		o.log.V(1).Infof("capacity check passed: %d replicas request %s, nodes allocate %s", o.newReplicas, requested, allocatable)
+		return nil
+	}
+
+	message := fmt.Sprintf("%d replicas of deployment %q request %s in total but the schedulable nodes only allocate %s, pods will likely stay Pending", o.newReplicas, o.deploymentName, requested, allocatable)
+	if o.strict {
+		return fmt.Errorf("%s (--strict)", message)
+	}
+	fmt.Fprintf(o.ErrOut, "Warning: %s\n", message)
+	return nil
+}
+
+//Requests of one pod: the sum of its containers, or the largest init container if that is bigger
+func podRequests(spec *corev1.PodSpec) capacity {
+	var total capacity
+	for _, container := range spec.Containers {
+		total.cpu += container.Resources.Requests.Cpu().MilliValue()
+		total.memory += container.Resources.Requests.Memory().Value()
+	}
+	for _, container := range spec.InitContainers {
+		if cpu := container.Resources.Requests.Cpu().MilliValue(); cpu > total.cpu {
+			total.cpu = cpu
+		}
+		if memory := container.Resources.Requests.Memory().Value(); memory > total.memory {
+			total.memory = memory
+		}
+	}
+	return total
+}
+
+//Allocatable capacity of nodes that are ready and not cordoned
+func schedulableCapacity(nodes []corev1.Node) capacity {
+	var total capacity
+	for _, node := range nodes {
+		if node.Spec.Unschedulable || !nodeReady(node) {
+			continue
+		}
+		total.cpu += node.Status.Allocatable.Cpu().MilliValue()
+		total.memory += node.Status.Allocatable.Memory().Value()
+	}
+	return total
+}
+
+func nodeReady(node corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func (c capacity) String() string {
+	cpu := resource.NewMilliQuantity(c.cpu, resource.DecimalSI)
+	memory := resource.NewQuantity(c.memory, resource.BinarySI)
+	return fmt.Sprintf("cpu %s and memory %s", cpu, memory)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+//Node with the given allocatable cpu and memory
+func testNode(name, cpu, memory string, ready, unschedulable bool) *corev1.Node {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			},
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+		},
+	}
+}
+
+//Deployment web whose pods request 500m cpu and 256Mi memory in two containers
+func requestingDeployment() *appsv1.Deployment {
+	deployment := testDeployment()
+	requests := func(cpu, memory string) corev1.ResourceRequirements {
+		return corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(cpu),
+			corev1.ResourceMemory: resource.MustParse(memory),
+		}}
+	}
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{
+		{Name: "web", Image: "registry.example.com/web:v1", Resources: requests("400m", "192Mi")},
+		{Name: "proxy", Image: "registry.example.com/proxy:v1", Resources: requests("100m", "64Mi")},
+	}
+	deployment.Spec.Template.Spec.InitContainers = []corev1.Container{
+		{Name: "migrate", Image: "registry.example.com/migrate:v1", Resources: requests("200m", "128Mi")},
+	}
+	return deployment
+}
+
+//Only the ready, schedulable node counts: 2 cpu and 4Gi
+func capacityObjects(deployment *appsv1.Deployment) []runtime.Object {
+	return []runtime.Object{
+		deployment,
+		testNode("node-1", "2", "4Gi", true, false),
+		testNode("node-2", "8", "16Gi", true, true),
+		testNode("node-3", "8", "16Gi", false, false),
+	}
+}
+
+func TestCheckCapacity(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+		warning string
+	}{
+		{name: "fits", args: []string{"--replicas=4"}},
+		{
+			name:    "exceeds",
+			args:    []string{"--replicas=5"},
+			warning: `Warning: 5 replicas of deployment "web" request cpu 2500m and memory 1280Mi in total but the schedulable nodes only allocate cpu 2 and memory 4Gi, pods will likely stay Pending`,
+		},
+		{
+			name:    "strict",
+			args:    []string{"--replicas=5", "--strict"},
+			wantErr: `5 replicas of deployment "web" request cpu 2500m and memory 1280Mi in total but the schedulable nodes only allocate cpu 2 and memory 4Gi, pods will likely stay Pending (--strict)`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRun(t, capacityObjects(requestingDeployment())...)
+
+			err := r.run(append([]string{"web", "-n", "team", "--check-capacity"}, tt.args...)...)
+			if len(tt.wantErr) > 0 {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("run = %v, want %q", err, tt.wantErr)
+				}
+				if updates := countUpdates(r); updates != 0 {
+					t.Errorf("got %d updates, want none", updates)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if got := strings.TrimSpace(r.errOut.String()); got != tt.warning {
+				t.Errorf("errOut = %q, want %q", got, tt.warning)
+			}
+			if updates := countUpdates(r); updates != 1 {
+				t.Errorf("got %d updates, want 1", updates)
+			}
+		})
+	}
+}
+
+//The larger init container sets the requests of a pod, not the sum with the containers
+func TestPodRequests(t *testing.T) {
+	spec := &requestingDeployment().Spec.Template.Spec
+	if got := podRequests(spec); got != (capacity{cpu: 500, memory: 256 << 20}) {
+		t.Errorf("podRequests = %s, want cpu 500m and memory 256Mi", got)
+	}
+	spec.InitContainers[0].Resources.Requests[corev1.ResourceCPU] = resource.MustParse("1")
+	if got := podRequests(spec); got != (capacity{cpu: 1000, memory: 256 << 20}) {
+		t.Errorf("podRequests = %s, want cpu 1 and memory 256Mi", got)
+	}
+}
+
+//Nodes are not listed when scaling down, keeping the replicas, without requests or without --check-capacity
+func TestCheckCapacitySkipped(t *testing.T) {
+	tests := []struct {
+		name       string
+		deployment *appsv1.Deployment
+		args       []string
+	}{
+		{name: "scale down", deployment: requestingDeployment(), args: []string{"--check-capacity", "--replicas=1"}},
+		{name: "same replicas", deployment: requestingDeployment(), args: []string{"--check-capacity", "--rhl=5"}},
+		{name: "no requests", deployment: testDeployment(), args: []string{"--check-capacity", "--replicas=50"}},
+		{name: "not enabled", deployment: requestingDeployment(), args: []string{"--replicas=50"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRun(t, tt.deployment)
+			if err := r.run(append([]string{"web", "-n", "team"}, tt.args...)...); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			for _, action := range r.clientset.Actions() {
+				if action.GetResource().Resource == "nodes" {
+					t.Errorf("nodes were listed: %v", action)
+				}
+			}
+			if strings.Contains(r.errOut.String(), "Warning") {
+				t.Errorf("unexpected warning:\n%s", r.errOut.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//Split the --replicas-from-configmap value "name/key"
+func parseConfigMapRef(ref string) (string, string, error) {
+	parts := strings.SplitN(ref, "/", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return "", "", fmt.Errorf("invalid --replicas-from-configmap %q, expected name/key", ref)
+	}
+	return parts[0], parts[1], nil
+}
+
+//Function to read the desired replicas from the ConfigMap key in the namespace of the deployment
+func (o *EditDeployOptions) replicasFromConfigMap() (int32, error) {
+	name, key, err := parseConfigMapRef(o.replicasConfigMap)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	configMap, err := o.clientset.CoreV1().ConfigMaps(o.namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	o.log.V(2).Infof("GET configmap %s/%s (%v)", o.namespace, name, time.Since(start))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read replicas from ConfigMap: %w", err)
+	}
+
+	value, ok := configMap.Data[key]
+	if !ok {
+		return 0, fmt.Errorf("ConfigMap %s/%s has no key %q", o.namespace, name, key)
+	}
+	replicas, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+	if err != nil || replicas < 0 {
+		return 0, fmt.Errorf("key %q of ConfigMap %s/%s is %q, expected a non-negative number of replicas", key, o.namespace, name, value)
+	}
+
+	o.log.V(1).Infof("replicas %d read from ConfigMap %s/%s key %q", replicas, o.namespace, name, key)
+	return int32(replicas), nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//ConfigMap sizing in namespace team holding data
+func testConfigMap(data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "sizing", Namespace: "team"},
+		Data:       data,
+	}
+}
+
+func TestReplicasFromConfigMap(t *testing.T) {
+	r := newTestRun(t, testDeployment(), testConfigMap(map[string]string{"web": " 7\n"}))
+	if err := r.run("web", "-n", "team", "--replicas-from-configmap=sizing/web"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	if got, want := r.out.String(), "Updated Deployment.. replicas=7, revisionHistoryLimit=10\n"; got != want {
+		t.Errorf("out = %q, want %q", got, want)
+	}
+	stored, err := r.clientset.AppsV1().Deployments("team").Get(context.TODO(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replicasOf(stored) != 7 {
+		t.Errorf("stored replicas = %d, want 7", replicasOf(stored))
+	}
+}
+
+func TestReplicasFromConfigMapErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		configMap *corev1.ConfigMap
+		ref       string
+		want      string
+	}{
+		{
+			name:      "missing key",
+			configMap: testConfigMap(map[string]string{"api": "2"}),
+			ref:       "sizing/web",
+			want:      `ConfigMap team/sizing has no key "web"`,
+		},
+		{
+			name:      "not a number",
+			configMap: testConfigMap(map[string]string{"web": "seven"}),
+			ref:       "sizing/web",
+			want:      `key "web" of ConfigMap team/sizing is "seven", expected a non-negative number of replicas`,
+		},
+		{
+			name:      "negative",
+			configMap: testConfigMap(map[string]string{"web": "-2"}),
+			ref:       "sizing/web",
+			want:      `key "web" of ConfigMap team/sizing is "-2", expected a non-negative number of replicas`,
+		},
+		{
+			name:      "too large",
+			configMap: testConfigMap(map[string]string{"web": "3000000000"}),
+			ref:       "sizing/web",
+			want:      `key "web" of ConfigMap team/sizing is "3000000000", expected a non-negative number of replicas`,
+		},
+		{
+			name: "missing configmap",
+			ref:  "sizing/web",
+			want: `failed to read replicas from ConfigMap: configmaps "sizing" not found`,
+		},
+		{
+			name: "malformed ref",
+			ref:  "sizing",
+			want: `invalid --replicas-from-configmap "sizing", expected name/key`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRun(t, testDeployment())
+			if tt.configMap != nil {
+				r = newTestRun(t, testDeployment(), tt.configMap)
+			}
+			err := r.complete("web", "-n", "team", "--replicas-from-configmap="+tt.ref)
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("complete = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplicasFromConfigMapWithReplicas(t *testing.T) {
+	r := newTestRun(t, testDeployment(), testConfigMap(map[string]string{"web": "7"}))
+	err := r.run("web", "-n", "team", "--replicas-from-configmap=sizing/web", "--replicas=2")
+	if want := "--replicas cannot be combined with --replicas-from-configmap"; err == nil || err.Error() != want {
+		t.Errorf("run = %v, want %q", err, want)
+	}
+}
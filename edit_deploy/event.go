@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"common/vlog"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//Reason and source of the event recorded by --emit-event
+const (
+	eventReason    = "ManualEdit"
+	eventComponent = "kubectl-edit-deploy"
+)
+
+//Function to record a ManualEdit event on the updated deployment so kubectl describe shows who changed what
+//The update already succeeded, a failure here only warns
+func (o *EditDeployOptions) recordEvent(before, updated *appsv1.Deployment) {
+	if before == nil || updated == nil {
+		return
+	}
+
+	now := metav1.NewTime(time.Now())
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: updated.Name + ".",
+			Namespace:    updated.Namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:            "Deployment",
+			APIVersion:      appsv1.SchemeGroupVersion.String(),
+			Name:            updated.Name,
+			Namespace:       updated.Namespace,
+			UID:             updated.UID,
+			ResourceVersion: updated.ResourceVersion,
+		},
+		Reason:         eventReason,
+		Message:        o.eventMessage(before, updated),
+		Type:           corev1.EventTypeNormal,
+		Source:         corev1.EventSource{Component: eventComponent},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+
+	start := time.Now()
+	_, err := o.clientset.CoreV1().Events(updated.Namespace).Create(context.TODO(), event, metav1.CreateOptions{})
+	o.log.V(2).Infof("POST event for deployment %s/%s (%v): %v", updated.Namespace, updated.Name, time.Since(start), vlog.ErrOrOK(err))
+	if err != nil {
+		fmt.Fprintf(o.ErrOut, "Warning: failed to record %s event on deployment %q: %v\n", eventReason, updated.Name, err)
+	}
+}
+
+//Message of the event, e.g. "replicas changed 3 -> 8 by kubectl-edit-deploy (user admin)"
+func (o *EditDeployOptions) eventMessage(before, updated *appsv1.Deployment) string {
+	changes := describeChanges(before, updated)
+	if len(changes) == 0 {
+		changes = []string{"deployment edited"}
+	}
+	message := fmt.Sprintf("%s by %s", strings.Join(changes, ", "), eventComponent)
+	if len(o.user) > 0 {
+		message += fmt.Sprintf(" (user %s)", o.user)
+	}
+	return message
+}
+
+//Human readable list of the spec fields that differ between the two objects
+func describeChanges(before, updated *appsv1.Deployment) []string {
+	var changes []string
+	if from, to := replicasOf(before), replicasOf(updated); from != to {
+		changes = append(changes, fmt.Sprintf("replicas changed %d -> %d", from, to))
+	}
+	if from, to := int32String(before.Spec.RevisionHistoryLimit), int32String(updated.Spec.RevisionHistoryLimit); from != to {
+		changes = append(changes, fmt.Sprintf("revisionHistoryLimit changed %s -> %s", from, to))
+	}
+	if from, to := int64String(before.Spec.Template.Spec.TerminationGracePeriodSeconds), int64String(updated.Spec.Template.Spec.TerminationGracePeriodSeconds); from != to {
+		changes = append(changes, fmt.Sprintf("terminationGracePeriodSeconds changed %s -> %s", from, to))
+	}
+	if before.Spec.Paused != updated.Spec.Paused {
+		changes = append(changes, fmt.Sprintf("paused changed %t -> %t", before.Spec.Paused, updated.Spec.Paused))
+	}
+	if before.Spec.Strategy.Type != updated.Spec.Strategy.Type {
+		changes = append(changes, fmt.Sprintf("strategy changed %s -> %s", before.Spec.Strategy.Type, updated.Spec.Strategy.Type))
+	}
+	return changes
+}
+
+func int32String(value *int32) string {
+	if value == nil {
+		return "unset"
+	}
+	return fmt.Sprint(*value)
+}
+
+func int64String(value *int64) string {
+	if value == nil {
+		return "unset"
+	}
+	return fmt.Sprint(*value)
+}
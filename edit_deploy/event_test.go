@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+//Function to capture the events created during the run, createErr fails every create
+func captureEvents(r *testRun, createErr error) *[]*corev1.Event {
+	var events []*corev1.Event
+	r.clientset.PrependReactor("create", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		events = append(events, action.(k8stesting.CreateAction).GetObject().(*corev1.Event).DeepCopy())
+		if createErr != nil {
+			return true, nil, createErr
+		}
+		return false, nil, nil
+	})
+	return &events
+}
+
+func TestEmitEvent(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	r.rawconfig = clientcmdapi.Config{
+		CurrentContext: "dev",
+		Contexts:       map[string]*clientcmdapi.Context{"dev": {AuthInfo: "alice", Namespace: "team"}},
+	}
+	events := captureEvents(r, nil)
+
+	if err := r.run("web", "--replicas=5", "--grace-period=20"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	if len(*events) != 1 {
+		t.Fatalf("got %d events, want 1", len(*events))
+	}
+	event := (*events)[0]
+	if event.Reason != "ManualEdit" || event.Type != corev1.EventTypeNormal || event.Source.Component != "kubectl-edit-deploy" {
+		t.Errorf("reason, type, source = %s, %s, %s", event.Reason, event.Type, event.Source.Component)
+	}
+	if want := "replicas changed 3 -> 5, terminationGracePeriodSeconds changed unset -> 20 by kubectl-edit-deploy (user alice)"; event.Message != want {
+		t.Errorf("message = %q, want %q", event.Message, want)
+	}
+	involved := event.InvolvedObject
+	if involved.Kind != "Deployment" || involved.APIVersion != "apps/v1" || involved.Name != "web" || involved.Namespace != "team" || involved.UID != testDeployment().UID {
+		t.Errorf("involvedObject = %+v, want deployment team/web", involved)
+	}
+	if event.Namespace != "team" || event.GenerateName != "web." {
+		t.Errorf("event namespace, generateName = %s, %s", event.Namespace, event.GenerateName)
+	}
+	if r.errOut.Len() > 0 {
+		t.Errorf("errOut = %q, want nothing", r.errOut.String())
+	}
+}
+
+func TestEmitEventFailureOnlyWarns(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	captureEvents(r, apierrors.NewForbidden(corev1.Resource("events"), "", errors.New(`User "alice" cannot create resource "events"`)))
+
+	if err := r.run("web", "-n", "team", "--replicas=5"); err != nil {
+		t.Fatalf("run = %v, want the edit to succeed", err)
+	}
+	if got, want := r.out.String(), "Updated Deployment.. replicas=5, revisionHistoryLimit=10\n"; got != want {
+		t.Errorf("out = %q, want %q", got, want)
+	}
+	if !strings.HasPrefix(r.errOut.String(), `Warning: failed to record ManualEdit event on deployment "web": `) {
+		t.Errorf("errOut = %q, want the warning", r.errOut.String())
+	}
+}
+
+func TestEmitEventDisabled(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	events := captureEvents(r, nil)
+
+	if err := r.run("web", "-n", "team", "--replicas=5", "--emit-event=false"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(*events) != 0 {
+		t.Errorf("got %d events with --emit-event=false, want none", len(*events))
+	}
+}
+
+func TestEmitEventNotOnDryRun(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	events := captureEvents(r, nil)
+
+	if err := r.run("web", "-n", "team", "--replicas=5", "--dry-run=server"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(*events) != 0 {
+		t.Errorf("got %d events on a dry run, want none", len(*events))
+	}
+}
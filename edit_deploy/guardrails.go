@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//Environment variable holding the default of --max-replicas
+const maxReplicasEnv = "EDIT_DEPLOY_MAX_REPLICAS"
+
+//Function to take --max-replicas from the environment unless the flag is given
+func (o *EditDeployOptions) completeMaxReplicas() error {
+	value, ok := os.LookupEnv(maxReplicasEnv)
+	if o.changedFlags["max-replicas"] || !ok || len(value) == 0 {
+		return nil
+	}
+	maxReplicas, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q, must be a number", maxReplicasEnv, value)
+	}
+	o.maxReplicas = int32(maxReplicas)
+	return nil
+}
+
+//Function to refuse replicas above --max-replicas, a typo like --replicas=300 should not reach the cluster
+//Only a change of the replicas is checked, other edits of a deployment already above the cap pass
+func (o *EditDeployOptions) validateMaxReplicas() error {
+	if o.maxReplicas < 0 {
+		return fmt.Errorf("invalid --max-replicas %d, must not be negative", o.maxReplicas)
+	}
+	if o.maxReplicas == 0 || o.newReplicas <= o.maxReplicas {
+		return nil
+	}
+	if o.live != nil && o.newReplicas == replicasOf(o.live) {
+		return nil
+	}
+	if o.force {
+		fmt.Fprintf(o.ErrOut, "Warning: %d replicas is above --max-replicas %d, continuing because of --force\n", o.newReplicas, o.maxReplicas)
+		return nil
+	}
+	return fmt.Errorf("%d replicas is above --max-replicas %d, pass --force if this is intended", o.newReplicas, o.maxReplicas)
+}
+
+//Function to refuse a manual replica change on a deployment scaled by a HorizontalPodAutoscaler
+//The autoscaler would revert it within its sync period, --force changes the replicas anyway
+//Without permission to list autoscalers only a warning is printed
+func (o *EditDeployOptions) checkAutoscaler() error {
+	if o.newReplicas == replicasOf(o.live) {
+		return nil
+	}
+
+	start := time.Now()
+	autoscalers, err := o.clientset.AutoscalingV1().HorizontalPodAutoscalers(o.namespace).List(context.TODO(), metav1.ListOptions{})
+	o.log.V(2).Infof("LIST horizontalpodautoscalers %s (%v)", o.namespace, time.Since(start))
+	//A user allowed to edit deployments may not be allowed to read autoscalers, the check is best effort then
+	if apierrors.IsForbidden(err) || apierrors.IsNotFound(err) {
+		fmt.Fprintf(o.ErrOut, "Warning: cannot check for a HorizontalPodAutoscaler scaling deployment %q: %v\n", o.deploymentName, err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list HorizontalPodAutoscalers: %w", err)
+	}
+
+	autoscaler := autoscalerFor(autoscalers.Items, o.deploymentName)
+	if autoscaler == nil {
+		return nil
+	}
+
+	message := fmt.Sprintf("HorizontalPodAutoscaler %q scales deployment %q, it will likely revert replicas=%d within its sync period", autoscaler.Name, o.deploymentName, o.newReplicas)
+	if !o.force {
+		return fmt.Errorf("%s; pass --force to change the replicas anyway", message)
+	}
+	fmt.Fprintf(o.ErrOut, "Warning: %s\n", message)
+	return nil
+}
+
+//The autoscaler whose scaleTargetRef is the named deployment, nil if there is none
+func autoscalerFor(autoscalers []autoscalingv1.HorizontalPodAutoscaler, deploymentName string) *autoscalingv1.HorizontalPodAutoscaler {
+	for i := range autoscalers {
+		target := autoscalers[i].Spec.ScaleTargetRef
+		if target.Kind == "Deployment" && target.Name == deploymentName {
+			return &autoscalers[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+//Autoscaler in namespace team scaling the named deployment
+func testAutoscaler(name, deploymentName string) *autoscalingv1.HorizontalPodAutoscaler {
+	return &autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team"},
+		Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: deploymentName, APIVersion: "apps/v1"},
+			MaxReplicas:    10,
+		},
+	}
+}
+
+func TestMaxReplicas(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		args    []string
+		wantErr string
+		warning string
+	}{
+		{name: "below", args: []string{"--replicas=5", "--max-replicas=20"}},
+		{name: "above", args: []string{"--replicas=50", "--max-replicas=20"}, wantErr: "50 replicas is above --max-replicas 20, pass --force if this is intended"},
+		{name: "force", args: []string{"--replicas=50", "--max-replicas=20", "--force"}, warning: "Warning: 50 replicas is above --max-replicas 20, continuing because of --force"},
+		{name: "env default", env: "20", args: []string{"--replicas=50"}, wantErr: "50 replicas is above --max-replicas 20, pass --force if this is intended"},
+		{name: "flag overrides env", env: "20", args: []string{"--replicas=50", "--max-replicas=0"}},
+		{name: "invalid env", env: "many", args: []string{"--replicas=5"}, wantErr: `invalid EDIT_DEPLOY_MAX_REPLICAS "many", must be a number`},
+		{name: "negative", args: []string{"--replicas=5", "--max-replicas=-1"}, wantErr: "invalid --max-replicas -1, must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRun(t, testDeployment())
+			t.Setenv(maxReplicasEnv, tt.env)
+
+			err := r.run(append([]string{"web", "-n", "team"}, tt.args...)...)
+			if len(tt.wantErr) > 0 {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("run = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if got := strings.TrimSpace(r.errOut.String()); got != tt.warning {
+				t.Errorf("errOut = %q, want %q", got, tt.warning)
+			}
+		})
+	}
+}
+
+func TestCheckAutoscaler(t *testing.T) {
+	const refused = `HorizontalPodAutoscaler "web" scales deployment "web", it will likely revert replicas=5 within its sync period; pass --force to change the replicas anyway`
+
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		args    []string
+		wantErr string
+		warning string
+	}{
+		{
+			name:    "targets this deployment",
+			objects: []runtime.Object{testDeployment(), testAutoscaler("web", "web")},
+			args:    []string{"--replicas=5"},
+			wantErr: refused,
+		},
+		{
+			name:    "force",
+			objects: []runtime.Object{testDeployment(), testAutoscaler("web", "web")},
+			args:    []string{"--replicas=5", "--force"},
+			warning: `Warning: HorizontalPodAutoscaler "web" scales deployment "web", it will likely revert replicas=5 within its sync period`,
+		},
+		{
+			name:    "targets another deployment",
+			objects: []runtime.Object{testDeployment(), testAutoscaler("api", "api")},
+			args:    []string{"--replicas=5"},
+		},
+		{
+			name:    "replicas unchanged",
+			objects: []runtime.Object{testDeployment(), testAutoscaler("web", "web")},
+			args:    []string{"--rhl=5"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRun(t, tt.objects...)
+			err := r.run(append([]string{"web", "-n", "team"}, tt.args...)...)
+			if len(tt.wantErr) > 0 {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("run = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if got := strings.TrimSpace(r.errOut.String()); got != tt.warning {
+				t.Errorf("errOut = %q, want %q", got, tt.warning)
+			}
+		})
+	}
+}
+
+func TestCheckAutoscalerListDenied(t *testing.T) {
+	resource := schema.GroupResource{Group: autoscalingv1.GroupName, Resource: "horizontalpodautoscalers"}
+	for name, listErr := range map[string]error{
+		"forbidden": apierrors.NewForbidden(resource, "", errors.New(`User "dev" cannot list resource "horizontalpodautoscalers"`)),
+		"not found": apierrors.NewNotFound(resource, ""),
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := newTestRun(t, testDeployment())
+			r.clientset.PrependReactor("list", "horizontalpodautoscalers", func(k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, listErr
+			})
+
+			if err := r.run("web", "-n", "team", "--replicas=5"); err != nil {
+				t.Fatalf("run = %v, want the edit to go ahead", err)
+			}
+			if !strings.HasPrefix(r.errOut.String(), `Warning: cannot check for a HorizontalPodAutoscaler scaling deployment "web"`) {
+				t.Errorf("errOut = %q, want the warning", r.errOut.String())
+			}
+			if !strings.HasPrefix(r.out.String(), "Updated Deployment.. replicas=5") {
+				t.Errorf("out = %q, want the update", r.out.String())
+			}
+		})
+	}
+}
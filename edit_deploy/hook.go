@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+//Runs the --pre-hook command, a field of the options so it can be stubbed without starting processes
+type hookRunner func(command string, env []string, out, errOut io.Writer) error
+
+//Function to run the hook through the shell of the platform
+func execHook(command string, env []string, out, errOut io.Writer) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = out
+	cmd.Stderr = errOut
+	return cmd.Run()
+}
+
+//Function to run --pre-hook for the current target, a failing hook aborts the edit
+//The hook gets the target as EDIT_DEPLOY_NAME and EDIT_DEPLOY_NAMESPACE
+//It runs from edit, so with --all-namespaces once per namespace, and never for a dry run which sends nothing
+func (o *EditDeployOptions) runPreHook() error {
+	if len(o.preHook) == 0 || o.dryRun != dryRunNone {
+		return nil
+	}
+
+	env := []string{
+		"EDIT_DEPLOY_NAME=" + o.deploymentName,
+		"EDIT_DEPLOY_NAMESPACE=" + o.namespace,
+	}
+	o.log.V(1).Infof("running pre-hook %q", o.preHook)
+	if err := o.runHook(o.preHook, env, o.Out, o.ErrOut); err != nil {
+		return fmt.Errorf("pre-hook failed, deployment %q not edited: %w", o.deploymentName, err)
+	}
+	return nil
+}
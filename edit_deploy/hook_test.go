@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+)
+
+//Hook stub recording the environment of every call
+type fakeHook struct {
+	mu    sync.Mutex
+	calls [][]string
+	err   error
+}
+
+func (h *fakeHook) run(command string, env []string, out, errOut io.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.calls = append(h.calls, env)
+	fmt.Fprintf(out, "hook %s ran\n", command)
+	return h.err
+}
+
+func countUpdates(r *testRun) int {
+	updates := 0
+	for _, action := range r.clientset.Actions() {
+		if action.GetVerb() == "update" && action.GetResource().Resource == "deployments" {
+			updates++
+		}
+	}
+	return updates
+}
+
+func TestPreHook(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	hook := &fakeHook{}
+	r.o.runHook = hook.run
+
+	if err := r.run("web", "-n", "team", "--replicas=5", "--pre-hook=./check.sh"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	if len(hook.calls) != 1 {
+		t.Fatalf("hook ran %d times, want once", len(hook.calls))
+	}
+	if want := []string{"EDIT_DEPLOY_NAME=web", "EDIT_DEPLOY_NAMESPACE=team"}; strings.Join(hook.calls[0], " ") != strings.Join(want, " ") {
+		t.Errorf("hook env = %q, want %q", hook.calls[0], want)
+	}
+	if got, want := r.out.String(), "hook ./check.sh ran\nUpdated Deployment.. replicas=5, revisionHistoryLimit=10\n"; got != want {
+		t.Errorf("out = %q, want %q", got, want)
+	}
+	if updates := countUpdates(r); updates != 1 {
+		t.Errorf("got %d updates, want 1", updates)
+	}
+}
+
+func TestPreHookFailureSkipsEdit(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	hook := &fakeHook{err: errors.New("exit status 3")}
+	r.o.runHook = hook.run
+
+	err := r.run("web", "-n", "team", "--replicas=5", "--pre-hook=./check.sh")
+	if want := `pre-hook failed, deployment "web" not edited: exit status 3`; err == nil || err.Error() != want {
+		t.Fatalf("run = %v, want %q", err, want)
+	}
+	if updates := countUpdates(r); updates != 0 {
+		t.Errorf("got %d updates after a failed hook, want none", updates)
+	}
+	if strings.Contains(r.out.String(), "Updated Deployment..") {
+		t.Errorf("out reports an update:\n%s", r.out.String())
+	}
+}
+
+//The hook is not run for a command that fails validation
+func TestPreHookNotRunOnInvalidFlags(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	hook := &fakeHook{}
+	r.o.runHook = hook.run
+
+	if err := r.run("web", "-n", "team", "--replicas=-1", "--pre-hook=./check.sh"); err == nil {
+		t.Fatal("run = nil, want the invalid replicas")
+	}
+	if len(hook.calls) != 0 {
+		t.Errorf("hook ran %d times, want never", len(hook.calls))
+	}
+}
+
+//The hook only fires for edits that are sent, not for refused, declined or dry-run ones
+func TestPreHookNotRunWithoutUpdate(t *testing.T) {
+	tests := []struct {
+		name      string
+		protected bool
+		answers   string
+		args      []string
+		wantErr   bool
+	}{
+		{name: "refused by protection", protected: true, args: []string{"--replicas=1"}, wantErr: true},
+		{name: "protection declined", protected: true, answers: "n\n", args: []string{"--replicas=1", "--override-protection"}},
+		{name: "diff declined", answers: "n\n", args: []string{"--replicas=5", "--diff"}},
+		{name: "client dry run", args: []string{"--replicas=5", "--dry-run"}},
+		{name: "server dry run", args: []string{"--replicas=5", "--dry-run=server"}},
+		{name: "diff dry run", args: []string{"--replicas=5", "--diff", "--dry-run"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRun(t, testDeployment())
+			if tt.protected {
+				protectNamespaces(t, "team")
+			}
+			r.in.WriteString(tt.answers)
+			hook := &fakeHook{}
+			r.o.runHook = hook.run
+
+			err := r.run(append([]string{"web", "-n", "team", "--pre-hook=./check.sh"}, tt.args...)...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("run = %v, want error %t", err, tt.wantErr)
+			}
+			if len(hook.calls) != 0 {
+				t.Errorf("hook ran %d times, want never", len(hook.calls))
+			}
+		})
+	}
+}
+
+//Confirmations come first, the hook runs once they are answered
+func TestPreHookAfterConfirmation(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	protectNamespaces(t, "team")
+	r.in.WriteString("y\n")
+	hook := &fakeHook{}
+	r.o.runHook = hook.run
+
+	if err := r.run("web", "-n", "team", "--replicas=1", "--override-protection", "--pre-hook=./check.sh"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	out := r.out.String()
+	prompt := strings.Index(out, "[y/N]: ")
+	ran := strings.Index(out, "hook ./check.sh ran")
+	if prompt < 0 || ran < prompt {
+		t.Errorf("hook did not run after the confirmation:\n%s", out)
+	}
+	if updates := countUpdates(r); updates != 1 {
+		t.Errorf("got %d updates, want 1", updates)
+	}
+}
+
+func TestPreHookPerNamespace(t *testing.T) {
+	var objects []k8sruntime.Object
+	for _, namespace := range []string{"team", "shop"} {
+		deployment := testDeployment()
+		deployment.Namespace = namespace
+		objects = append(objects, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}, deployment)
+	}
+	r := newTestRun(t, objects...)
+	hook := &fakeHook{}
+	r.o.runHook = hook.run
+
+	if err := r.run("web", "-A", "--replicas=5", "--pre-hook=./check.sh"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	var namespaces []string
+	for _, env := range hook.calls {
+		namespaces = append(namespaces, env[1])
+	}
+	sort.Strings(namespaces)
+	if want := []string{"EDIT_DEPLOY_NAMESPACE=shop", "EDIT_DEPLOY_NAMESPACE=team"}; strings.Join(namespaces, " ") != strings.Join(want, " ") {
+		t.Errorf("hook namespaces = %q, want %q", namespaces, want)
+	}
+}
+
+func TestExecHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	var out, errOut bytes.Buffer
+	env := []string{"EDIT_DEPLOY_NAME=web", "EDIT_DEPLOY_NAMESPACE=team"}
+
+	if err := execHook(`echo "$EDIT_DEPLOY_NAMESPACE/$EDIT_DEPLOY_NAME"; echo warn >&2`, env, &out, &errOut); err != nil {
+		t.Fatalf("execHook: %v", err)
+	}
+	if out.String() != "team/web\n" || errOut.String() != "warn\n" {
+		t.Errorf("out, errOut = %q, %q", out.String(), errOut.String())
+	}
+
+	if err := execHook("exit 3", env, &out, &errOut); err == nil || err.Error() != "exit status 3" {
+		t.Errorf("execHook(exit 3) = %v, want exit status 3", err)
+	}
+}
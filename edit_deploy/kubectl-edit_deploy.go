@@ -1,17 +1,34 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
-
+	"github.com/spf13/pflag"
+
+	"common/apicheck"
+	"common/apiserver"
+	"common/diff"
+	"common/exitcode"
+	"common/flagerr"
+	"common/prompt"
+	"common/protection"
+	"common/vlog"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/kubernetes"
 	v1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/client-go/util/retry"
 )
 
@@ -24,20 +41,169 @@ var (
 	# --rhl = edit revison history limit in current namespace 	
 	%[1]s edit-deploy <deploymentname> --rhl=<number>
 	
+	# --dry-run = only show the result, server also runs admission without persisting
+	%[1]s edit-deploy <deploymentname> --replicas=<number> --dry-run=server
+	
+	# --wait = block until the rollout finished or --timeout passed, then print its duration, recreated pods and restarts
+	%[1]s edit-deploy <deploymentname> --replicas=<number> --wait --timeout=2m
+	
+	# --diff = show the yaml diff and ask before applying, --yes skips the question
+	%[1]s edit-deploy <deploymentname> --replicas=<number> --diff
+	
+	# --show-diff = print what the update changed once it succeeded
+	%[1]s edit-deploy <deploymentname> --replicas=<number> --show-diff
+	
+	# --verify = read the deployment back and warn if the stored replicas differ from the requested ones
+	%[1]s edit-deploy <deploymentname> --replicas=<number> --verify
+	
+	# --label/--annotation = set key=value or remove key-, --pod-template also edits the pod template
+	%[1]s edit-deploy <deploymentname> --label=tier=backend --annotation=owner- --pod-template
+	
+	# --strategy = switch between RollingUpdate and Recreate, --max-surge/--max-unavailable tune RollingUpdate
+	%[1]s edit-deploy <deploymentname> --strategy=RollingUpdate --max-surge=1 --max-unavailable=0
+	
+	# --action = run a preset, e.g. emergency-scale-up, freeze, thaw or standby
+	%[1]s edit-deploy <deploymentname> --action=freeze --diff
+	
+	# --all-namespaces = edit the deployment in every namespace that has it, --namespace-selector narrows the namespaces
+	%[1]s edit-deploy <deploymentname> --rhl=2 -A --namespace-selector=team=platform --concurrency=8
+	
+	# --max-replicas = refuse more replicas than the cap (default $EDIT_DEPLOY_MAX_REPLICAS), --force overrides it and a HorizontalPodAutoscaler conflict
+	%[1]s edit-deploy <deploymentname> --replicas=<number> --max-replicas=50 --force
+	
+	# --pre-hook = run a command before the edit, a non-zero exit aborts it
+	%[1]s edit-deploy <deploymentname> --replicas=<number> --pre-hook="./check-freeze.sh"
+	
+	# --check-capacity = warn if the new replicas cannot fit the nodes, --strict refuses the edit instead
+	%[1]s edit-deploy <deploymentname> --replicas=<number> --check-capacity --strict
+	
+	# --grace-period = set terminationGracePeriodSeconds of the pods (0-3600)
+	%[1]s edit-deploy <deploymentname> --grace-period=120
+	
+	# --replicas-from-configmap = take the replicas from a ConfigMap key in the same namespace
+	%[1]s edit-deploy <deploymentname> --replicas-from-configmap=<configmapname>/<key>
+	
+	# --service = target the deployment backing a Service instead of naming it
+	%[1]s edit-deploy --service=<servicename> --replicas=<number>
+	
+	# --resource-version = update only if nobody changed the deployment since, exit code 5 on a conflict
+	%[1]s edit-deploy <deploymentname> --replicas=<number> --resource-version=<resourceversion>
+	
+	# --timings = print how many conflict retries the update needed and how long it took, -v logs the same
+	%[1]s edit-deploy <deploymentname> --replicas=<number> --timings
+	
+	# --override-protection = allow scale-downs and pod restarts in the protectedNamespaces of --config, always asks first
+	%[1]s edit-deploy <deploymentname> --replicas=0 --namespace=kube-system --override-protection
+	
+	# --apiserver = try these API server endpoints in order and use the first one responding
+	%[1]s edit-deploy <deploymentname> --replicas=<number> --apiserver=https://10.0.0.1:6443 --apiserver=https://10.0.0.2:6443
+	
+	# --emit-event = record a ManualEdit event on the deployment after the update (default true)
+	%[1]s edit-deploy <deploymentname> --replicas=<number> --emit-event=false
+	
 	`
 )
 
+//Upper bound of --grace-period, longer drains are better handled outside of pod shutdown
+const maxGracePeriodSeconds = 3600
+
+//Values accepted by --dry-run
+const (
+	dryRunNone   = "none"
+	dryRunClient = "client"
+	dryRunServer = "server"
+)
+
 //Struct having all the flags arguments variable
 type EditDeployOptions struct {
 	configFlags *genericclioptions.ConfigFlags
 
+	clientset         kubernetes.Interface
 	deploymentsClient v1.DeploymentInterface
+	discoveryClient   discovery.DiscoveryInterface
 	newReplicas       int32
 	newRhl            int32 //Change here
 	deploymentName    string
+	namespace         string
+	live              *appsv1.Deployment
+
+	gracePeriod int64
+	//nil unless --grace-period is given, zero is a valid value
+	terminationGracePeriod *int64
+
+	//"name/key" of a ConfigMap holding the replicas
+	replicasConfigMap string
+
+	//Service whose selector picks the deployment instead of the name argument
+	service string
+
+	allNamespaces     bool
+	namespaceSelector string
+	concurrency       int
+
+	//Update only if the deployment still has this resourceVersion, no retry on conflict
+	expectedResourceVersion string
+
+	//Names of the flags given on the command line
+	changedFlags map[string]bool
+
+	dryRun   string
+	wait     bool
+	timeout  time.Duration
+	showDiff bool
+	yes      bool
+	//Print what the update changed after it succeeded
+	showChanges bool
+	//Get the deployment again after the update and compare the stored replicas
+	verify bool
+
+	labelArgs             []string
+	annotationArgs        []string
+	labelChanges          metadataChanges
+	annotationChanges     metadataChanges
+	podTemplate           bool
+	forceSelectorMismatch bool
+
+	strategy       string
+	maxSurge       string
+	maxUnavailable string
+
+	action     string
+	actionPlan []string
+	paused     *bool
+
+	preHook string
+	runHook hookRunner
+
+	//Record a ManualEdit event on the deployment, user is the auth info of the kubeconfig context
+	emitEvent bool
+	user      string
+
+	capacityCheck bool
+	strict        bool
+
+	//protectedNamespaces of the config file and whether destructive edits there are allowed
+	configPath         string
+	protection         *protection.Config
+	overrideProtection bool
+
+	//Zero means no cap, the default comes from EDIT_DEPLOY_MAX_REPLICAS
+	maxReplicas int32
+	force       bool
+
+	//Endpoints of --apiserver, the first one responding is used
+	apiservers []string
+
+	verbosity int
+	log       *vlog.Logger
+	//Report the retries and duration of the update without raising the verbosity
+	timings bool
 
 	args []string
 
+	//Reader of In shared by every confirmation, so answers given ahead are not lost
+	answers *bufio.Reader
+
 	genericclioptions.IOStreams
 }
 
@@ -45,6 +211,8 @@ type EditDeployOptions struct {
 func NewEditDeploymentOptions(streams genericclioptions.IOStreams) *EditDeployOptions {
 	return &EditDeployOptions{
 		configFlags: genericclioptions.NewConfigFlags(true),
+		runHook:     execHook,
+		answers:     bufio.NewReader(streams.In),
 		IOStreams:   streams,
 	}
 }
@@ -52,11 +220,15 @@ func NewEditDeploymentOptions(streams genericclioptions.IOStreams) *EditDeployOp
 //Cobra provides easy cli interface with error handling and easy extensibility(aliases, suggestions, depreciated, etc.) of cli tools
 //https://cobra.dev/
 func NewCmdEdit(streams genericclioptions.IOStreams) *cobra.Command {
-	o := NewEditDeploymentOptions(streams)
+	return newCmdEdit(NewEditDeploymentOptions(streams))
+}
 
+//Command bound to the given options, tests parse flags into their own options
+func newCmdEdit(o *EditDeployOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:          "edit-deploy [deployment_name] [flags]",
 		Short:        "View or edit current replicas",
+		Long:         "View or edit current replicas\n\n" + exitcode.Help,
 		Example:      fmt.Sprintf(editExample, "kubectl"),
 		SilenceUsage: true,
 		//RunE function runs when .execute is called with error handling
@@ -79,29 +251,73 @@ func NewCmdEdit(streams genericclioptions.IOStreams) *cobra.Command {
 	//Store newReplicas value in variable
 	cmd.Flags().Int32Var(&o.newReplicas, "replicas", o.newReplicas, "Number of Replicas to set")
 	cmd.Flags().Int32Var(&o.newRhl, "rhl", -1, "Revision History limit")
+	cmd.Flags().StringVar(&o.service, "service", "", "Edit the deployment whose pods are selected by this Service instead of naming it")
+	cmd.Flags().StringVar(&o.replicasConfigMap, "replicas-from-configmap", "", "Read the replicas from a ConfigMap key given as name/key")
+	cmd.Flags().Int64Var(&o.gracePeriod, "grace-period", 0, "terminationGracePeriodSeconds of the pod template, 0 to 3600")
+	cmd.Flags().StringVar(&o.expectedResourceVersion, "resource-version", "", "Update only if the deployment still has this resourceVersion, fail with exit code 5 on a conflict instead of retrying")
+	cmd.Flags().StringVar(&o.dryRun, "dry-run", dryRunNone, "Must be \"none\", \"client\" or \"server\", client only prints the change and server submits it without persisting")
+	cmd.Flags().Lookup("dry-run").NoOptDefVal = dryRunClient
+	cmd.Flags().BoolVar(&o.wait, "wait", false, "Wait until the rollout of the deployment finished")
+	cmd.Flags().DurationVar(&o.timeout, "timeout", 5*time.Minute, "How long --wait waits for the rollout")
+	cmd.Flags().BoolVar(&o.showDiff, "diff", false, "Print the yaml diff of the live and the edited deployment and ask before applying it")
+	cmd.Flags().BoolVar(&o.showChanges, "show-diff", false, "Print the diff of the deployment before and after the update once it succeeded")
+	cmd.Flags().BoolVar(&o.verify, "verify", false, "Get the deployment again after the update and warn if the stored replicas differ, e.g. changed by a mutating webhook")
+	cmd.Flags().BoolVar(&o.yes, "yes", false, "Apply the change shown by --diff without asking")
+	cmd.Flags().StringArrayVar(&o.labelArgs, "label", nil, "Label to set as key=value or to remove as key-, can be repeated")
+	cmd.Flags().StringArrayVar(&o.annotationArgs, "annotation", nil, "Annotation to set as key=value or to remove as key-, can be repeated")
+	cmd.Flags().BoolVar(&o.podTemplate, "pod-template", false, "Also apply --label and --annotation to the pod template")
+	cmd.Flags().BoolVar(&o.forceSelectorMismatch, "force-selector-mismatch", false, "Allow --pod-template to change labels used by the selector")
+	cmd.Flags().StringVar(&o.strategy, "strategy", "", "Update strategy to set, \"RollingUpdate\" or \"Recreate\"")
+	cmd.Flags().StringVar(&o.maxSurge, "max-surge", "", "RollingUpdate maxSurge as number or percentage, e.g. 1 or 25%")
+	cmd.Flags().StringVar(&o.maxUnavailable, "max-unavailable", "", "RollingUpdate maxUnavailable as number or percentage, e.g. 0 or 25%")
+	cmd.Flags().StringVar(&o.action, "action", "", actionUsage())
+	cmd.Flags().BoolVarP(&o.allNamespaces, "all-namespaces", "A", false, "Edit the deployment in every namespace that has it")
+	cmd.Flags().StringVar(&o.namespaceSelector, "namespace-selector", "", "Label selector limiting the namespaces of --all-namespaces, e.g. team=platform")
+	cmd.Flags().IntVar(&o.concurrency, "concurrency", 4, "Number of namespaces edited in parallel with --all-namespaces")
+	cmd.Flags().BoolVar(&o.capacityCheck, "check-capacity", false, "Warn when the requested replicas need more cpu or memory than the schedulable nodes allocate")
+	cmd.Flags().BoolVar(&o.strict, "strict", false, "Fail instead of warning when --check-capacity finds too little capacity")
+	cmd.Flags().BoolVar(&o.emitEvent, "emit-event", true, "Record a ManualEdit event on the deployment after a successful update")
+	cmd.Flags().Int32Var(&o.maxReplicas, "max-replicas", 0, "Refuse to set more replicas than this, 0 disables the cap, defaults to $"+maxReplicasEnv)
+	cmd.Flags().BoolVar(&o.force, "force", false, "Go ahead despite --max-replicas or a HorizontalPodAutoscaler scaling the deployment")
+	cmd.Flags().StringVar(&o.preHook, "pre-hook", "", "Command run right before the update with EDIT_DEPLOY_NAME and EDIT_DEPLOY_NAMESPACE set, after every check and confirmation, not on --dry-run, a non-zero exit aborts the edit")
+	cmd.Flags().BoolVar(&o.timings, "timings", false, "Print how many conflict retries the update needed and how long it took")
+	protection.AddFlags(cmd.Flags(), &o.configPath, &o.overrideProtection)
+	apiserver.AddFlags(cmd.Flags(), &o.apiservers)
+	vlog.AddFlags(cmd.Flags(), &o.verbosity)
 	//Add extra flags provided by user
 	o.configFlags.AddFlags(cmd.Flags())
+
+	//Value syntax printed when one of these flags is given without a value
+	flagerr.SetSyntax(cmd.Flags(), "replicas-from-configmap", "name/key of a ConfigMap in the namespace")
+	flagerr.SetSyntax(cmd.Flags(), "timeout", "a duration, e.g. 90s or 2m")
+	flagerr.SetSyntax(cmd.Flags(), "label", "key=value to set or key- to remove")
+	flagerr.SetSyntax(cmd.Flags(), "annotation", "key=value to set or key- to remove")
+	flagerr.SetSyntax(cmd.Flags(), "strategy", "RollingUpdate or Recreate")
+	flagerr.SetSyntax(cmd.Flags(), "max-surge", "a number or percentage, e.g. 1 or 25%")
+	flagerr.SetSyntax(cmd.Flags(), "max-unavailable", "a number or percentage, e.g. 0 or 25%")
+	flagerr.SetSyntax(cmd.Flags(), "action", "one of "+strings.Join(actionNames(), ", "))
+	flagerr.SetSyntax(cmd.Flags(), "namespace-selector", "a label selector, e.g. team=platform")
+	cmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		return flagerr.Handle(c.Flags(), err)
+	})
 	return cmd
 }
 
 //Function to store all flags and arguments in struct
 func (o *EditDeployOptions) Complete(cmd *cobra.Command, args []string) error {
-	o.args = args
-
-	if len(args) > 0 {
-		o.deploymentName = args[0]
+	if err := o.completeFlags(cmd, args); err != nil {
+		return err
 	}
 
-	if len(o.deploymentName) == 0 {
-
-		return fmt.Errorf("deployment name not specified")
-
-	}
 	config, err := o.configFlags.ToRESTConfig()
 	if err != nil {
 		return err
 	}
 
+	if config, err = apiserver.Resolve(config, *o.configFlags.APIServer, o.apiservers, o.log); err != nil {
+		return err
+	}
+
 	//Rawconfig for extracting the current namespace
 	rawconfig, err := o.configFlags.ToRawKubeConfigLoader().RawConfig()
 	if err != nil {
@@ -114,57 +330,267 @@ func (o *EditDeployOptions) Complete(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	return o.completeTarget(clientset, rawconfig)
+}
+
+//Function to store the flags and arguments that need no cluster access
+func (o *EditDeployOptions) completeFlags(cmd *cobra.Command, args []string) error {
+	o.args = args
+	o.log = vlog.New(o.ErrOut, o.verbosity)
+	o.changedFlags = map[string]bool{}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		o.changedFlags[f.Name] = true
+	})
+
+	if len(args) > 0 {
+		o.deploymentName = args[0]
+	}
+
+	if len(o.deploymentName) == 0 && len(o.service) == 0 {
+
+		return fmt.Errorf("deployment name not specified")
+
+	}
+
+	if cmd.Flags().Changed("grace-period") {
+		o.terminationGracePeriod = &o.gracePeriod
+	}
+
+	if err := o.completeMaxReplicas(); err != nil {
+		return err
+	}
+
+	var err error
+	if o.protection, err = protection.Load(o.configPath); err != nil {
+		return err
+	}
+
+	if o.labelChanges, err = parseMetadataChanges("label", o.labelArgs); err != nil {
+		return err
+	}
+	if o.annotationChanges, err = parseMetadataChanges("annotation", o.annotationArgs); err != nil {
+		return err
+	}
+	return nil
+}
+
+//Function to resolve the context, namespace and target deployment with the clientset, tests pass a fake one
+func (o *EditDeployOptions) completeTarget(clientset kubernetes.Interface, rawconfig clientcmdapi.Config) error {
+	//--context overrides the current context of the kubeconfig
+	contextName := rawconfig.CurrentContext
+	if len(*o.configFlags.Context) > 0 {
+		contextName = *o.configFlags.Context
+	}
+	o.log.V(1).Infof("using context %q", contextName)
+	if kubeContext, ok := rawconfig.Contexts[contextName]; ok {
+		o.user = kubeContext.AuthInfo
+	}
+
+	o.clientset = clientset
+	o.discoveryClient = clientset.Discovery()
+
+	//Namespaces and their deployments clients are resolved in Run
+	if o.allNamespaces {
+		o.log.V(1).Infof("target deployment %s in all namespaces matching %q", o.deploymentName, o.namespaceSelector)
+		return nil
+	}
+
 	//If namespace is provided in the flags
 	userSpecifiedNamespace := *o.configFlags.Namespace
+	namespaceSource := "--namespace flag"
 
 	//If not specified use namespace in current context
 	if len(userSpecifiedNamespace) == 0 {
-		userSpecifiedNamespace = rawconfig.Contexts[rawconfig.CurrentContext].Namespace
-
+		if kubeContext, ok := rawconfig.Contexts[contextName]; ok {
+			userSpecifiedNamespace = kubeContext.Namespace
+		}
+		namespaceSource = fmt.Sprintf("context %q", contextName)
 	}
 
 	//If current context is empty then use "default" namespace
 	if len(userSpecifiedNamespace) == 0 {
 		userSpecifiedNamespace = "Default"
+		namespaceSource = "fallback"
 	}
+	o.namespace = userSpecifiedNamespace
+	o.log.V(1).Infof("using namespace %q from %s", o.namespace, namespaceSource)
 
 	//Get deployment client in the specified namespace
 	o.deploymentsClient = clientset.AppsV1().Deployments(userSpecifiedNamespace)
+
+	//The name argument is checked in Validate, --service must not override it silently
+	if len(o.service) > 0 && len(o.deploymentName) == 0 {
+		var err error
+		if o.deploymentName, err = o.resolveService(); err != nil {
+			return err
+		}
+	}
+	o.log.V(1).Infof("target deployment %s/%s", o.namespace, o.deploymentName)
+
+	return o.loadTarget()
+}
+
+//Function to fetch the target deployment and fill in everything derived from it
+func (o *EditDeployOptions) loadTarget() error {
+	start := time.Now()
 	result, getErr := o.deploymentsClient.Get(context.TODO(), o.deploymentName, metav1.GetOptions{})
+	o.log.V(2).Infof("GET deployment %s/%s (%v)", o.namespace, o.deploymentName, time.Since(start))
 	if getErr != nil {
-		return getErr
+		return apicheck.Unavailable(o.discoveryClient, appsv1.SchemeGroupVersion, getErr)
 	}
-
-	if o.newReplicas <= 0 {
-		o.newReplicas = *result.Spec.Replicas
+	o.live = result
+	o.log.V(1).Infof("fetched deployment %s/%s resourceVersion %s with replicas=%d", o.namespace, o.deploymentName, result.ResourceVersion, replicasOf(result))
+
+	//Keep the live value unless --replicas is given, so --replicas=0 scales to zero
+	switch {
+	case len(o.replicasConfigMap) > 0:
+		replicas, err := o.replicasFromConfigMap()
+		if err != nil {
+			return err
+		}
+		o.newReplicas = replicas
+	case !o.changedFlags["replicas"]:
+		o.newReplicas = replicasOf(result)
 	}
 
-	if o.newRhl < 0 {
+	if !o.changedFlags["rhl"] && result.Spec.RevisionHistoryLimit != nil {
 		o.newRhl = *result.Spec.RevisionHistoryLimit
 	}
 
-	return nil
+	return o.expandAction()
 }
 
 //Function to validate if the arguments and flags are correct
 func (o *EditDeployOptions) Validate() error {
-	if len(o.args) != 1 {
+	if len(o.service) > 0 {
+		if len(o.args) != 0 {
+			return fmt.Errorf("--service replaces the deployment name argument, pass only one of them")
+		}
+	} else if len(o.args) != 1 {
 		return fmt.Errorf("only one argument is allowed")
 	}
 
-	if o.newReplicas <= 0 {
+	if o.newReplicas < 0 {
 		return fmt.Errorf("invalid number of replicas")
 	}
 
-	if o.newRhl < 0 {
+	if err := o.validateMaxReplicas(); err != nil {
+		return err
+	}
+
+	if o.changedFlags["rhl"] && o.newRhl < 0 {
 		return fmt.Errorf("invalid value of RevisionHistoryLimit")
 	}
 
-	return nil
+	if len(o.replicasConfigMap) > 0 {
+		if o.changedFlags["replicas"] {
+			return fmt.Errorf("--replicas cannot be combined with --replicas-from-configmap")
+		}
+		if len(o.action) > 0 {
+			return fmt.Errorf("--replicas-from-configmap cannot be combined with --action")
+		}
+		if _, _, err := parseConfigMapRef(o.replicasConfigMap); err != nil {
+			return err
+		}
+	}
+
+	if o.terminationGracePeriod != nil {
+		if *o.terminationGracePeriod < 0 {
+			return fmt.Errorf("invalid --grace-period %d, must not be negative", *o.terminationGracePeriod)
+		}
+		if *o.terminationGracePeriod > maxGracePeriodSeconds {
+			return fmt.Errorf("--grace-period %d is above %d seconds (1 hour), pods that need that long to stop should drain in-flight work differently, e.g. hand it off to a queue", *o.terminationGracePeriod, maxGracePeriodSeconds)
+		}
+	}
+
+	if err := o.validateMetadata(); err != nil {
+		return err
+	}
+
+	if err := o.validateStrategy(); err != nil {
+		return err
+	}
+
+	return o.validateFlagCombinations()
+}
+
+//Reject flags that contradict each other instead of silently ignoring one of them
+func (o *EditDeployOptions) validateFlagCombinations() error {
+	switch o.dryRun {
+	case dryRunNone, dryRunClient, dryRunServer:
+	default:
+		return fmt.Errorf("invalid --dry-run value %q, must be one of \"none\", \"client\" or \"server\"", o.dryRun)
+	}
+
+	if o.wait && o.dryRun != dryRunNone {
+		return fmt.Errorf("--wait cannot be combined with --dry-run=%s: a dry run never starts a rollout, drop one of the two flags", o.dryRun)
+	}
+
+	if o.verify && o.dryRun != dryRunNone {
+		return fmt.Errorf("--verify cannot be combined with --dry-run=%s: nothing is stored to verify, drop one of the two flags", o.dryRun)
+	}
+
+	if o.changedFlags["timeout"] && !o.wait {
+		return fmt.Errorf("--timeout only applies to --wait, add --wait or drop --timeout")
+	}
+
+	if o.wait && o.timeout <= 0 {
+		return fmt.Errorf("invalid --timeout %v, must be greater than zero", o.timeout)
+	}
+
+	if len(o.action) > 0 {
+		if _, err := lookupAction(o.action); err != nil {
+			return err
+		}
+	}
+
+	if o.strict && !o.capacityCheck {
+		return fmt.Errorf("--strict only applies to --check-capacity")
+	}
+
+	return o.validateAllNamespaces()
 }
 
 //Function to update the deployments
 func (o *EditDeployOptions) Run() error {
+	if o.allNamespaces {
+		return o.runAllNamespaces()
+	}
+	return o.edit()
+}
+
+//Function to edit the single deployment loaded by loadTarget
+func (o *EditDeployOptions) edit() error {
+	//Pre-flight of the resolved namespace, before the plan, checks and diff of the edit
+	proceed, err := o.checkProtection()
+	if err != nil || !proceed {
+		return err
+	}
+
+	o.warnMissingMetadata()
+	o.printActionPlan()
+
+	if err := o.checkCapacity(); err != nil {
+		return err
+	}
+
+	if err := o.checkAutoscaler(); err != nil {
+		return err
+	}
+
+	o.log.V(1).Infof("computed change for deployment %s/%s: %s", o.namespace, o.deploymentName, o.changeSummary())
+
+	if o.showDiff {
+		proceed, err := o.confirmDiff()
+		if err != nil || !proceed {
+			return err
+		}
+	}
+
+	//Last step before the update, so the hook only fires for edits that are sent
+	if err := o.runPreHook(); err != nil {
+		return err
+	}
 
 	//RetryOnConflict make an update to a resource when other code also doing change at same time
 	//If conflict occurs it will wait for sometime
@@ -175,38 +601,219 @@ func (o *EditDeployOptions) Run() error {
 	// 	Jitter:   0.1,
 	// }
 	//https://pkg.go.dev/k8s.io/apimachinery/pkg/util/wait#Backoff
-	retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+
+	//The object as fetched and as returned by the last attempt, used to describe the change
+	var before, updated *appsv1.Deployment
+	//When the server accepted the update, the start of the rollout measured by --wait
+	var accepted time.Time
+	attempt := 0
+	updateStart := time.Now()
+	update := func() error {
+		attempt++
+		o.log.V(2).Infof("update attempt %d", attempt)
 
 		//Get the specified deployment
 		//passing empty context
 		//Since no information required for Get like deadline, cancellation etc.
 
+		start := time.Now()
 		result, getErr := o.deploymentsClient.Get(context.TODO(), o.deploymentName, metav1.GetOptions{})
+		o.log.V(2).Infof("GET deployment %s/%s (%v)", o.namespace, o.deploymentName, time.Since(start))
 
 		if getErr != nil {
-			return fmt.Errorf("failed to get latest version fo Deployment: %v", getErr)
+			return fmt.Errorf("failed to get latest version fo Deployment: %w", getErr)
+		}
+
+		//The server rejects the update with a conflict if the deployment moved past the expected version
+		if len(o.expectedResourceVersion) > 0 {
+			result.ResourceVersion = o.expectedResourceVersion
+		}
+
+		//Every requested field goes into this one Update, a second round trip could leave the edit half applied
+		before = result.DeepCopy()
+		o.applyChanges(result)
+
+		//Client dry run stops before anything is sent
+		if o.dryRun == dryRunClient {
+			return nil
+		}
+
+		updateOptions := metav1.UpdateOptions{}
+		if o.dryRun == dryRunServer {
+			updateOptions.DryRun = []string{metav1.DryRunAll}
 		}
 
-		result.Spec.Replicas = &o.newReplicas
-		result.Spec.RevisionHistoryLimit = &o.newRhl
-		_, updateErr := o.deploymentsClient.Update(context.TODO(), result, metav1.UpdateOptions{})
+		start = time.Now()
+		var updateErr error
+		updated, updateErr = o.deploymentsClient.Update(context.TODO(), result, updateOptions)
+		o.log.V(2).Infof("PUT deployment %s/%s (%v): %v", o.namespace, o.deploymentName, time.Since(start), vlog.ErrOrOK(updateErr))
+		if updateErr == nil {
+			accepted = time.Now()
+			diff.Log(o.log, result, updated)
+		}
 		return updateErr
-	})
+	}
+
+	//--resource-version is a single shot, a conflict means someone else changed the deployment
+	var retryErr error
+	if len(o.expectedResourceVersion) > 0 {
+		retryErr = update()
+		if apierrors.IsConflict(retryErr) {
+			return fmt.Errorf("deployment %q was modified since resourceVersion %s, not retrying: %w", o.deploymentName, o.expectedResourceVersion, retryErr)
+		}
+	} else {
+		retryErr = retry.RetryOnConflict(retry.DefaultRetry, update)
+	}
 
 	if retryErr != nil {
-		return fmt.Errorf("update failed: %v", retryErr)
+		return fmt.Errorf("update failed: %w", apicheck.Unavailable(o.discoveryClient, appsv1.SchemeGroupVersion, retryErr))
+	}
+	if o.dryRun != dryRunClient {
+		o.reportRetries(attempt, time.Since(updateStart))
+	}
+
+	switch o.dryRun {
+	case dryRunClient:
+		fmt.Fprintf(o.Out, "Updated Deployment.. (dry run) %s\n", o.changeSummary())
+	case dryRunServer:
+		fmt.Fprintf(o.Out, "Updated Deployment.. (server dry run) %s\n", o.changeSummary())
+		o.printChanges(before, updated)
+	default:
+		fmt.Fprintf(o.Out, "Updated Deployment.. %s\n", o.changeSummary())
+		o.printChanges(before, updated)
+		if o.emitEvent {
+			o.recordEvent(before, updated)
+		}
+		if o.verify {
+			o.verifyReplicas()
+		}
+	}
+
+	if o.wait {
+		return o.waitForRollout(accepted)
 	}
-	fmt.Println("Updated Deployment..")
 
 	return nil
 }
 
+//Function to set every requested field on the deployment, used for the update and for --diff
+//New mutations belong here so they are sent in the same Update as the others
+func (o *EditDeployOptions) applyChanges(deployment *appsv1.Deployment) {
+	deployment.Spec.Replicas = &o.newReplicas
+	if o.newRhl >= 0 {
+		deployment.Spec.RevisionHistoryLimit = &o.newRhl
+	}
+	if o.paused != nil {
+		deployment.Spec.Paused = *o.paused
+	}
+	if o.terminationGracePeriod != nil {
+		gracePeriod := *o.terminationGracePeriod
+		deployment.Spec.Template.Spec.TerminationGracePeriodSeconds = &gracePeriod
+	}
+
+	deployment.Labels = o.labelChanges.apply(deployment.Labels)
+	deployment.Annotations = o.annotationChanges.apply(deployment.Annotations)
+	if o.podTemplate {
+		template := &deployment.Spec.Template
+		template.Labels = o.labelChanges.apply(template.Labels)
+		template.Annotations = o.annotationChanges.apply(template.Annotations)
+	}
+
+	o.applyStrategy(deployment)
+}
+
+//The applied fields printed in the success message
+func (o *EditDeployOptions) changeSummary() string {
+	summary := fmt.Sprintf("replicas=%d", o.newReplicas)
+	if o.newRhl >= 0 {
+		summary += fmt.Sprintf(", revisionHistoryLimit=%d", o.newRhl)
+	}
+	if o.terminationGracePeriod != nil {
+		summary += fmt.Sprintf(", terminationGracePeriodSeconds=%d", *o.terminationGracePeriod)
+	}
+	return summary
+}
+
+//Function to print the diff of the live and the edited deployment and ask whether to apply it
+//A dry run only prints the diff and stops, --yes applies without asking
+func (o *EditDeployOptions) confirmDiff() (bool, error) {
+	proposed := o.live.DeepCopy()
+	o.applyChanges(proposed)
+
+	objectDiff, err := diff.Objects(o.live, proposed, diff.Options{From: "live/" + o.deploymentName, To: "edited/" + o.deploymentName, Context: 3})
+	if err != nil {
+		return false, fmt.Errorf("cannot diff deployment: %w", err)
+	}
+	if len(objectDiff) == 0 {
+		fmt.Fprintln(o.Out, "No changes to apply..")
+		return false, nil
+	}
+
+	if prompt.IsTerminal(o.Out) {
+		objectDiff = diff.Colorize(objectDiff)
+	}
+	fmt.Fprint(o.Out, objectDiff)
+
+	if o.dryRun != dryRunNone {
+		return false, nil
+	}
+	if o.yes {
+		return true, nil
+	}
+
+	apply, err := prompt.Confirm(o.answers, o.Out, "Apply these changes?")
+	if err != nil {
+		return false, err
+	}
+	if !apply {
+		fmt.Fprintln(o.Out, "Edit cancelled..")
+	}
+	return apply, nil
+}
+
+//Function to report how many conflicts the update ran into, a high count means others keep changing the deployment
+func (o *EditDeployOptions) reportRetries(attempts int, elapsed time.Duration) {
+	message := fmt.Sprintf("update of deployment %s/%s succeeded after %d retries in %v", o.namespace, o.deploymentName, attempts-1, elapsed.Round(time.Millisecond))
+	if o.timings {
+		fmt.Fprintln(o.ErrOut, message)
+		return
+	}
+	o.log.V(1).Infof("%s", message)
+}
+
+//Function to print the diff of the deployment as fetched and as returned by the update, --show-diff
+func (o *EditDeployOptions) printChanges(before, after *appsv1.Deployment) {
+	if !o.showChanges || before == nil || after == nil {
+		return
+	}
+	if err := diff.PrintChanged(o.Out, before, after, o.deploymentName); err != nil {
+		fmt.Fprintf(o.ErrOut, "Warning: cannot diff deployment: %v\n", err)
+	}
+}
+
+//Function to read the deployment back and warn when the stored replicas are not the requested ones
+//Admission webhooks may change the object without failing the update
+func (o *EditDeployOptions) verifyReplicas() {
+	start := time.Now()
+	persisted, err := o.deploymentsClient.Get(context.TODO(), o.deploymentName, metav1.GetOptions{})
+	o.log.V(2).Infof("GET deployment %s/%s (%v)", o.namespace, o.deploymentName, time.Since(start))
+	if err != nil {
+		fmt.Fprintf(o.ErrOut, "Warning: cannot verify deployment %q: %v\n", o.deploymentName, err)
+		return
+	}
+	if replicas := replicasOf(persisted); replicas != o.newReplicas {
+		fmt.Fprintf(o.ErrOut, "Warning: deployment %q has replicas=%d stored but %d was requested, an admission webhook or controller changed it\n", o.deploymentName, replicas, o.newReplicas)
+		return
+	}
+	fmt.Fprintf(o.Out, "Verified deployment %q has replicas=%d\n", o.deploymentName, o.newReplicas)
+}
+
 func main() {
 	flags := flag.NewFlagSet("kubectl-edit_deploy", flag.ExitOnError)
 	flag.CommandLine = flags
 
 	root := NewCmdEdit(genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr})
 	if err := root.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(exitcode.For(err))
 	}
 }
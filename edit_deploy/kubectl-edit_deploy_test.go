@@ -0,0 +1,448 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"common/protection"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+//Options and fake clientset of one command run, with the output it wrote
+type testRun struct {
+	o         *EditDeployOptions
+	clientset *fake.Clientset
+	//Kubeconfig the context, user and default namespace are taken from
+	rawconfig clientcmdapi.Config
+	in        *bytes.Buffer
+	out       *bytes.Buffer
+	errOut    *bytes.Buffer
+}
+
+//Function to return a run against a fake clientset holding objects
+//The config file and the --max-replicas default of the environment are cleared so tests do not depend on the machine
+func newTestRun(t *testing.T, objects ...runtime.Object) *testRun {
+	t.Helper()
+	t.Setenv(protection.EnvVar, filepath.Join(t.TempDir(), "edit-plugins.yaml"))
+	t.Setenv(maxReplicasEnv, "")
+
+	streams, in, out, errOut := genericclioptions.NewTestIOStreams()
+	return &testRun{
+		o:         NewEditDeploymentOptions(streams),
+		clientset: fake.NewSimpleClientset(objects...),
+		in:        in,
+		out:       out,
+		errOut:    errOut,
+	}
+}
+
+//Function to parse args like the command line and complete the options against the fake clientset
+func (r *testRun) complete(args ...string) error {
+	cmd := newCmdEdit(r.o)
+	if err := cmd.ParseFlags(args); err != nil {
+		return err
+	}
+	if err := r.o.completeFlags(cmd, cmd.Flags().Args()); err != nil {
+		return err
+	}
+	return r.o.completeTarget(r.clientset, r.rawconfig)
+}
+
+//Function to go through Complete, Validate and Run like the command does
+func (r *testRun) run(args ...string) error {
+	if err := r.complete(args...); err != nil {
+		return err
+	}
+	if err := r.o.Validate(); err != nil {
+		return err
+	}
+	return r.o.Run()
+}
+
+//Function to point the config file at one protecting the namespaces
+func protectNamespaces(t *testing.T, namespaces ...string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "edit-plugins.yaml")
+	content := "protectedNamespaces: [" + strings.Join(namespaces, ", ") + "]\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(protection.EnvVar, path)
+}
+
+//Deployment web in namespace team with 3 replicas and revisionHistoryLimit 10
+func testDeployment() *appsv1.Deployment {
+	replicas := int32(3)
+	rhl := int32(10)
+	labels := map[string]string{"app": "web"}
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web",
+			Namespace: "team",
+			UID:       "6f1c2b1e-web",
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas:             &replicas,
+			RevisionHistoryLimit: &rhl,
+			Selector:             &metav1.LabelSelector{MatchLabels: labels},
+			Strategy:             appsv1.DeploymentStrategy{Type: appsv1.RollingUpdateDeploymentStrategyType},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "web", Image: "registry.example.com/web:v1"}},
+				},
+			},
+		},
+	}
+}
+
+func TestLevelZeroOutput(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	if err := r.run("web", "-n", "team", "--replicas=5"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	if got, want := r.out.String(), "Updated Deployment.. replicas=5, revisionHistoryLimit=10\n"; got != want {
+		t.Errorf("out = %q, want %q", got, want)
+	}
+	if got := r.errOut.String(); got != "" {
+		t.Errorf("errOut = %q, want nothing at level 0", got)
+	}
+}
+
+func TestVerboseOnlyWritesErrOut(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	if err := r.run("web", "-n", "team", "--replicas=5", "-v=2"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	if got, want := r.out.String(), "Updated Deployment.. replicas=5, revisionHistoryLimit=10\n"; got != want {
+		t.Errorf("out = %q, want %q", got, want)
+	}
+	for _, want := range []string{`using namespace "team" from --namespace flag`, "GET deployment team/web", "PUT deployment team/web"} {
+		if !bytes.Contains(r.errOut.Bytes(), []byte(want)) {
+			t.Errorf("errOut misses %q:\n%s", want, r.errOut.String())
+		}
+	}
+}
+
+func TestAllChangesInOneUpdate(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	updates := 0
+	var sent *appsv1.Deployment
+	r.clientset.PrependReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		updates++
+		sent = action.(k8stesting.UpdateAction).GetObject().(*appsv1.Deployment).DeepCopy()
+		return false, nil, nil
+	})
+
+	err := r.run("web", "-n", "team", "--replicas=5", "--rhl=4", "--annotation=owner=platform", "--label=tier=frontend", "--grace-period=45", "--strategy=Recreate")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	if updates != 1 {
+		t.Fatalf("got %d updates, want exactly one", updates)
+	}
+	if got := replicasOf(sent); got != 5 {
+		t.Errorf("replicas = %d, want 5", got)
+	}
+	if got := sent.Spec.RevisionHistoryLimit; got == nil || *got != 4 {
+		t.Errorf("revisionHistoryLimit = %s, want 4", int32String(got))
+	}
+	if got := sent.Annotations["owner"]; got != "platform" {
+		t.Errorf("annotation owner = %q, want platform", got)
+	}
+	if got := sent.Labels["tier"]; got != "frontend" {
+		t.Errorf("label tier = %q, want frontend", got)
+	}
+	if got := sent.Spec.Template.Spec.TerminationGracePeriodSeconds; got == nil || *got != 45 {
+		t.Errorf("terminationGracePeriodSeconds = %s, want 45", int64String(got))
+	}
+	if got := sent.Spec.Strategy.Type; got != appsv1.RecreateDeploymentStrategyType {
+		t.Errorf("strategy = %s, want Recreate", got)
+	}
+	if sent.Spec.Strategy.RollingUpdate != nil {
+		t.Errorf("rollingUpdate = %+v, want nil with Recreate", sent.Spec.Strategy.RollingUpdate)
+	}
+}
+
+func TestValidateFlagCombinations(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--dry-run=later"}, `invalid --dry-run value "later", must be one of "none", "client" or "server"`},
+		{[]string{"--wait", "--dry-run"}, "--wait cannot be combined with --dry-run=client: a dry run never starts a rollout, drop one of the two flags"},
+		{[]string{"--wait", "--dry-run=server"}, "--wait cannot be combined with --dry-run=server: a dry run never starts a rollout, drop one of the two flags"},
+		{[]string{"--verify", "--dry-run=client"}, "--verify cannot be combined with --dry-run=client: nothing is stored to verify, drop one of the two flags"},
+		{[]string{"--verify", "--dry-run=server"}, "--verify cannot be combined with --dry-run=server: nothing is stored to verify, drop one of the two flags"},
+		{[]string{"--timeout=2m"}, "--timeout only applies to --wait, add --wait or drop --timeout"},
+		{[]string{"--wait", "--timeout=0s"}, "invalid --timeout 0s, must be greater than zero"},
+		{[]string{"--wait", "--timeout=-1m"}, "invalid --timeout -1m0s, must be greater than zero"},
+		{[]string{"--strict"}, "--strict only applies to --check-capacity"},
+		{[]string{"--namespace-selector=team=platform"}, "--namespace-selector only applies to --all-namespaces"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args[len(tt.args)-1], func(t *testing.T) {
+			r := newTestRun(t, testDeployment())
+			if err := r.complete(append([]string{"web", "-n", "team"}, tt.args...)...); err != nil {
+				t.Fatalf("complete: %v", err)
+			}
+			err := r.o.validateFlagCombinations()
+			if err == nil {
+				t.Fatalf("validateFlagCombinations(%v) = nil, want %q", tt.args, tt.want)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("validateFlagCombinations(%v) = %q, want %q", tt.args, err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateFlagCombinationsAccepts(t *testing.T) {
+	for _, args := range [][]string{
+		{"--dry-run=none", "--wait", "--timeout=2m"},
+		{"--dry-run=server"},
+		{"--verify"},
+		{"--check-capacity", "--strict"},
+	} {
+		r := newTestRun(t, testDeployment())
+		if err := r.complete(append([]string{"web", "-n", "team"}, args...)...); err != nil {
+			t.Fatalf("complete: %v", err)
+		}
+		if err := r.o.validateFlagCombinations(); err != nil {
+			t.Errorf("validateFlagCombinations(%v) = %v, want nil", args, err)
+		}
+	}
+}
+
+//The protection prompt and the --diff prompt read from the same input, both answers must arrive
+func TestConfirmationsShareInput(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	protectNamespaces(t, "team")
+	r.in.WriteString("y\nyes\n")
+
+	if err := r.run("web", "-n", "team", "--replicas=1", "--override-protection", "--diff"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	out := r.out.String()
+	for _, want := range []string{
+		`Run scale-down 3 -> 1 in protected namespace "team"? [y/N]: `,
+		"Apply these changes? [y/N]: ",
+		"Updated Deployment.. replicas=1, revisionHistoryLimit=10\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("out misses %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Edit cancelled..") {
+		t.Errorf("second confirmation lost its answer:\n%s", out)
+	}
+}
+
+//Function to fail the first n deployment updates with a conflict, later updates reach the tracker
+func conflictUpdates(r *testRun, n int) *int {
+	updates := 0
+	r.clientset.PrependReactor("update", "deployments", func(k8stesting.Action) (bool, runtime.Object, error) {
+		updates++
+		if updates <= n {
+			return true, nil, apierrors.NewConflict(appsv1.Resource("deployments"), "web", errors.New("the object has been modified"))
+		}
+		return false, nil, nil
+	})
+	return &updates
+}
+
+func TestReportRetries(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	updates := conflictUpdates(r, 2)
+
+	if err := r.run("web", "-n", "team", "--replicas=5", "--timings"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if *updates != 3 {
+		t.Errorf("got %d updates, want 3", *updates)
+	}
+	if !strings.HasPrefix(r.errOut.String(), "update of deployment team/web succeeded after 2 retries in ") {
+		t.Errorf("errOut = %q, want the retry report", r.errOut.String())
+	}
+	if got, want := r.out.String(), "Updated Deployment.. replicas=5, revisionHistoryLimit=10\n"; got != want {
+		t.Errorf("out = %q, want %q", got, want)
+	}
+}
+
+func TestReportRetriesOnlyWithTimingsOrVerbose(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	conflictUpdates(r, 1)
+	if err := r.run("web", "-n", "team", "--replicas=5"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if r.errOut.Len() > 0 {
+		t.Errorf("errOut = %q, want nothing without --timings", r.errOut.String())
+	}
+
+	r = newTestRun(t, testDeployment())
+	conflictUpdates(r, 1)
+	if err := r.run("web", "-n", "team", "--replicas=5", "-v"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if !strings.Contains(r.errOut.String(), "update of deployment team/web succeeded after 1 retries in ") {
+		t.Errorf("errOut misses the retry report at -v:\n%s", r.errOut.String())
+	}
+}
+
+//--resource-version is a single attempt, a conflict is reported instead of retried
+func TestResourceVersionNotRetried(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	updates := conflictUpdates(r, 1)
+
+	err := r.run("web", "-n", "team", "--replicas=5", "--resource-version=42")
+	if err == nil || !strings.HasPrefix(err.Error(), `deployment "web" was modified since resourceVersion 42, not retrying: `) || !apierrors.IsConflict(err) {
+		t.Fatalf("run = %v, want the wrapped conflict", err)
+	}
+	if *updates != 1 {
+		t.Errorf("got %d updates, want 1", *updates)
+	}
+}
+
+//Function to answer the get after the update, the one of --verify, with replicas or with err
+func alterVerifyGet(r *testRun, replicas int32, err error) {
+	updated := false
+	r.clientset.PrependReactor("update", "deployments", func(k8stesting.Action) (bool, runtime.Object, error) {
+		updated = true
+		return false, nil, nil
+	})
+	r.clientset.PrependReactor("get", "deployments", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if !updated {
+			return false, nil, nil
+		}
+		if err != nil {
+			return true, nil, err
+		}
+		stored, getErr := r.clientset.Tracker().Get(appsv1.SchemeGroupVersion.WithResource("deployments"), "team", "web")
+		if getErr != nil {
+			return true, nil, getErr
+		}
+		altered := stored.(*appsv1.Deployment).DeepCopy()
+		altered.Spec.Replicas = &replicas
+		return true, altered, nil
+	})
+}
+
+func TestVerifyReplicas(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	if err := r.run("web", "-n", "team", "--replicas=5", "--verify"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got, want := r.out.String(), "Updated Deployment.. replicas=5, revisionHistoryLimit=10\nVerified deployment \"web\" has replicas=5\n"; got != want {
+		t.Errorf("out = %q, want %q", got, want)
+	}
+	if r.errOut.Len() > 0 {
+		t.Errorf("errOut = %q, want no warning", r.errOut.String())
+	}
+}
+
+func TestVerifyReplicasChangedAfterUpdate(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	alterVerifyGet(r, 2, nil)
+	if err := r.run("web", "-n", "team", "--replicas=5", "--verify"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	want := "Warning: deployment \"web\" has replicas=2 stored but 5 was requested, an admission webhook or controller changed it\n"
+	if got := r.errOut.String(); got != want {
+		t.Errorf("errOut = %q, want %q", got, want)
+	}
+	if strings.Contains(r.out.String(), "Verified") {
+		t.Errorf("out reports a verified deployment:\n%s", r.out.String())
+	}
+}
+
+//A failed read back only warns, the update itself went through
+func TestVerifyReplicasGetFails(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	alterVerifyGet(r, 0, apierrors.NewForbidden(appsv1.Resource("deployments"), "web", errors.New("no get")))
+	if err := r.run("web", "-n", "team", "--replicas=5", "--verify"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if want := "Warning: cannot verify deployment \"web\": "; !strings.HasPrefix(r.errOut.String(), want) {
+		t.Errorf("errOut = %q, want prefix %q", r.errOut.String(), want)
+	}
+	if countUpdates(r) != 1 {
+		t.Errorf("got %d updates, want 1", countUpdates(r))
+	}
+}
+
+//--diff --dry-run prints the diff of the live and the edited deployment and stops before the update
+func TestDiffDryRun(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	if err := r.run("web", "-n", "team", "--replicas=5", "--diff", "--dry-run"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	want := "--- live/web\n" +
+		"+++ edited/web\n" +
+		"@@ -6,7 +6,7 @@\n" +
+		"   namespace: team\n" +
+		"   uid: 6f1c2b1e-web\n" +
+		" spec:\n" +
+		"-  replicas: 3\n" +
+		"+  replicas: 5\n" +
+		"   revisionHistoryLimit: 10\n" +
+		"   selector:\n" +
+		"     matchLabels:\n"
+	if got := r.out.String(); got != want {
+		t.Errorf("out =\n%s\nwant\n%s", got, want)
+	}
+	if updates := countUpdates(r); updates != 0 {
+		t.Errorf("got %d updates on a dry run, want none", updates)
+	}
+}
+
+//--diff --yes prints the same diff and applies it without asking
+func TestDiffYes(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	if err := r.run("web", "-n", "team", "--replicas=5", "--diff", "--yes"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	out := r.out.String()
+	if !strings.HasPrefix(out, "--- live/web\n+++ edited/web\n@@ -6,7 +6,7 @@\n") || !strings.Contains(out, "-  replicas: 3\n+  replicas: 5\n") {
+		t.Errorf("out misses the diff:\n%s", out)
+	}
+	if strings.Contains(out, "Apply these changes?") {
+		t.Errorf("--yes asked for confirmation:\n%s", out)
+	}
+	if !strings.HasSuffix(out, "Updated Deployment.. replicas=5, revisionHistoryLimit=10\n") {
+		t.Errorf("out misses the update:\n%s", out)
+	}
+	if updates := countUpdates(r); updates != 1 {
+		t.Errorf("got %d updates, want 1", updates)
+	}
+}
+
+func TestDiffNoChanges(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	if err := r.run("web", "-n", "team", "--replicas=3", "--diff", "--yes"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got, want := r.out.String(), "No changes to apply..\n"; got != want {
+		t.Errorf("out = %q, want %q", got, want)
+	}
+	if updates := countUpdates(r); updates != 0 {
+		t.Errorf("got %d updates, want none", updates)
+	}
+}
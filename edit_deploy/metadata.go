@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+//Changes requested by the repeatable --label or --annotation flag
+//"key=value" sets a key and "key-" removes it, same as kubectl label/annotate
+type metadataChanges struct {
+	set    map[string]string
+	remove []string
+}
+
+//Function to parse the raw flag values, syntax of keys and values is checked in Validate
+func parseMetadataChanges(flagName string, values []string) (metadataChanges, error) {
+	changes := metadataChanges{set: map[string]string{}}
+	for _, value := range values {
+		switch {
+		case strings.Contains(value, "="):
+			parts := strings.SplitN(value, "=", 2)
+			changes.set[parts[0]] = parts[1]
+		case strings.HasSuffix(value, "-"):
+			changes.remove = append(changes.remove, strings.TrimSuffix(value, "-"))
+		default:
+			return changes, fmt.Errorf("invalid --%s %q, expected key=value or key- to remove", flagName, value)
+		}
+	}
+
+	for _, key := range changes.remove {
+		if _, ok := changes.set[key]; ok {
+			return changes, fmt.Errorf("--%s sets and removes %q at the same time", flagName, key)
+		}
+	}
+	return changes, nil
+}
+
+func (c metadataChanges) copy() metadataChanges {
+	copied := metadataChanges{set: make(map[string]string, len(c.set)), remove: append([]string{}, c.remove...)}
+	for key, value := range c.set {
+		copied.set[key] = value
+	}
+	return copied
+}
+
+func (c metadataChanges) empty() bool {
+	return len(c.set) == 0 && len(c.remove) == 0
+}
+
+//Sorted keys of all set and removed entries
+func (c metadataChanges) keys() []string {
+	keys := append([]string{}, c.remove...)
+	for key := range c.set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//Function to apply the changes to a label or annotation map, the map is created if needed
+func (c metadataChanges) apply(m map[string]string) map[string]string {
+	if c.empty() {
+		return m
+	}
+	if m == nil {
+		m = map[string]string{}
+	}
+	for key, value := range c.set {
+		m[key] = value
+	}
+	for _, key := range c.remove {
+		delete(m, key)
+	}
+	return m
+}
+
+//Keys that are asked to be removed but do not exist in m
+func (c metadataChanges) missing(m map[string]string) []string {
+	var missing []string
+	for _, key := range c.remove {
+		if _, ok := m[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+//Function to check label keys and values against the Kubernetes syntax
+func validateLabels(changes metadataChanges) error {
+	for _, key := range changes.keys() {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid label key %q: %s", key, strings.Join(errs, "; "))
+		}
+	}
+	for key, value := range changes.set {
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("invalid value %q for label %q: %s", value, key, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+//Function to check annotation keys, values may be any string
+func validateAnnotations(changes metadataChanges) error {
+	for _, key := range changes.keys() {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid annotation key %q: %s", key, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+//Label keys the selector of the deployment depends on
+func selectorKeys(selector *metav1.LabelSelector) map[string]bool {
+	keys := map[string]bool{}
+	if selector == nil {
+		return keys
+	}
+	for key := range selector.MatchLabels {
+		keys[key] = true
+	}
+	for _, requirement := range selector.MatchExpressions {
+		keys[requirement.Key] = true
+	}
+	return keys
+}
+
+//Function to validate --label, --annotation and the selector protection of --pod-template
+func (o *EditDeployOptions) validateMetadata() error {
+	if err := validateLabels(o.labelChanges); err != nil {
+		return err
+	}
+	if err := validateAnnotations(o.annotationChanges); err != nil {
+		return err
+	}
+
+	if o.podTemplate && o.labelChanges.empty() && o.annotationChanges.empty() {
+		return fmt.Errorf("--pod-template needs --label or --annotation")
+	}
+	if o.forceSelectorMismatch && !o.podTemplate {
+		return fmt.Errorf("--force-selector-mismatch only applies to --pod-template")
+	}
+
+	//Template labels matched by the selector must stay, otherwise the deployment no longer owns its pods
+	if o.podTemplate && !o.forceSelectorMismatch && o.live != nil {
+		critical := selectorKeys(o.live.Spec.Selector)
+		for _, key := range o.labelChanges.keys() {
+			if critical[key] {
+				return fmt.Errorf("label %q is used by the selector of deployment %q, changing it on the pod template breaks the deployment; pass --force-selector-mismatch to do it anyway", key, o.deploymentName)
+			}
+		}
+	}
+
+	return nil
+}
+
+//Function to warn about removals of keys that do not exist, they are not an error
+func (o *EditDeployOptions) warnMissingMetadata() {
+	if o.live == nil {
+		return
+	}
+	for _, key := range o.labelChanges.missing(o.live.Labels) {
+		fmt.Fprintf(o.ErrOut, "Warning: label %q not found on deployment %q\n", key, o.deploymentName)
+	}
+	for _, key := range o.annotationChanges.missing(o.live.Annotations) {
+		fmt.Fprintf(o.ErrOut, "Warning: annotation %q not found on deployment %q\n", key, o.deploymentName)
+	}
+	if !o.podTemplate {
+		return
+	}
+	template := o.live.Spec.Template
+	for _, key := range o.labelChanges.missing(template.Labels) {
+		fmt.Fprintf(o.ErrOut, "Warning: label %q not found on the pod template of deployment %q\n", key, o.deploymentName)
+	}
+	for _, key := range o.annotationChanges.missing(template.Annotations) {
+		fmt.Fprintf(o.ErrOut, "Warning: annotation %q not found on the pod template of deployment %q\n", key, o.deploymentName)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseMetadataChanges(t *testing.T) {
+	tests := []struct {
+		values     []string
+		wantSet    map[string]string
+		wantRemove []string
+		wantErr    string
+	}{
+		{values: nil, wantSet: map[string]string{}},
+		{values: []string{"tier=frontend", "owner-"}, wantSet: map[string]string{"tier": "frontend"}, wantRemove: []string{"owner"}},
+		{values: []string{"tier="}, wantSet: map[string]string{"tier": ""}},
+		{values: []string{"query=a=b"}, wantSet: map[string]string{"query": "a=b"}},
+		{values: []string{"tier=web", "tier=api"}, wantSet: map[string]string{"tier": "api"}},
+		{values: []string{"tier"}, wantErr: `invalid --label "tier", expected key=value or key- to remove`},
+		{values: []string{"tier=web", "tier-"}, wantErr: `--label sets and removes "tier" at the same time`},
+	}
+	for _, tt := range tests {
+		changes, err := parseMetadataChanges("label", tt.values)
+		if len(tt.wantErr) > 0 {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("parse(%q) = %v, want %q", tt.values, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parse(%q): %v", tt.values, err)
+			continue
+		}
+		if !reflect.DeepEqual(changes.set, tt.wantSet) || !reflect.DeepEqual(changes.remove, tt.wantRemove) {
+			t.Errorf("parse(%q) = %+v, want set %v remove %v", tt.values, changes, tt.wantSet, tt.wantRemove)
+		}
+	}
+}
+
+func TestMetadataChangesApply(t *testing.T) {
+	changes, err := parseMetadataChanges("label", []string{"tier=frontend", "owner-", "gone-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := changes.apply(map[string]string{"app": "web", "owner": "team", "tier": "backend"})
+	if want := map[string]string{"app": "web", "tier": "frontend"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("apply = %v, want %v", got, want)
+	}
+	if got := changes.apply(nil); !reflect.DeepEqual(got, map[string]string{"tier": "frontend"}) {
+		t.Errorf("apply(nil) = %v", got)
+	}
+	if missing := changes.missing(map[string]string{"owner": "team"}); !reflect.DeepEqual(missing, []string{"gone"}) {
+		t.Errorf("missing = %q, want [gone]", missing)
+	}
+	if keys := changes.keys(); !reflect.DeepEqual(keys, []string{"gone", "owner", "tier"}) {
+		t.Errorf("keys = %q", keys)
+	}
+}
+
+func TestValidateMetadata(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"label key", []string{"--label=bad key=x"}, `invalid label key "bad key": `},
+		{"label value", []string{"--label=tier=front end"}, `invalid value "front end" for label "tier": `},
+		{"annotation key", []string{"--annotation=-owner=x"}, `invalid annotation key "-owner": `},
+		{"removed label key", []string{"--label=bad/key/x-"}, `invalid label key "bad/key/x": `},
+		{"pod template alone", []string{"--pod-template"}, "--pod-template needs --label or --annotation"},
+		{"force without pod template", []string{"--label=app=api", "--force-selector-mismatch"}, "--force-selector-mismatch only applies to --pod-template"},
+		{"selector label", []string{"--pod-template", "--label=app=api"}, `label "app" is used by the selector of deployment "web", changing it on the pod template breaks the deployment; pass --force-selector-mismatch to do it anyway`},
+		{"selector label removed", []string{"--pod-template", "--label=app-"}, `label "app" is used by the selector of deployment "web"`},
+		{"selector expression", []string{"--pod-template", "--label=track=canary"}, `label "track" is used by the selector of deployment "web"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := testDeployment()
+			deployment.Spec.Selector.MatchExpressions = []metav1.LabelSelectorRequirement{{Key: "track", Operator: metav1.LabelSelectorOpExists}}
+			r := newTestRun(t, deployment)
+			err := r.run(append([]string{"web", "-n", "team"}, tt.args...)...)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Fatalf("run = %v, want %q", err, tt.want)
+			}
+			if updates := countUpdates(r); updates != 0 {
+				t.Errorf("got %d updates, want none", updates)
+			}
+		})
+	}
+}
+
+func TestValidateMetadataAccepts(t *testing.T) {
+	for _, args := range [][]string{
+		{"--label=example.com/tier=frontend", "--annotation=note=any text, even with spaces"},
+		{"--label=app=web"},
+		{"--pod-template", "--label=tier=frontend"},
+		{"--pod-template", "--annotation=app=anything"},
+		{"--pod-template", "--label=app=api", "--force-selector-mismatch"},
+	} {
+		r := newTestRun(t, testDeployment())
+		if err := r.complete(append([]string{"web", "-n", "team"}, args...)...); err != nil {
+			t.Fatalf("complete(%q): %v", args, err)
+		}
+		if err := r.o.validateMetadata(); err != nil {
+			t.Errorf("validateMetadata(%q) = %v, want nil", args, err)
+		}
+	}
+}
+
+func TestMetadataApplied(t *testing.T) {
+	deployment := testDeployment()
+	deployment.Annotations = map[string]string{"owner": "team"}
+
+	r := newTestRun(t, deployment)
+	if err := r.run("web", "-n", "team", "--label=tier=frontend", "--annotation=owner-", "--annotation=oncall=platform"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	stored, err := r.clientset.AppsV1().Deployments("team").Get(context.TODO(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"app": "web", "tier": "frontend"}; !reflect.DeepEqual(stored.Labels, want) {
+		t.Errorf("labels = %v, want %v", stored.Labels, want)
+	}
+	if want := map[string]string{"oncall": "platform"}; !reflect.DeepEqual(stored.Annotations, want) {
+		t.Errorf("annotations = %v, want %v", stored.Annotations, want)
+	}
+	//Without --pod-template the pods are left alone
+	if want := map[string]string{"app": "web"}; !reflect.DeepEqual(stored.Spec.Template.Labels, want) {
+		t.Errorf("template labels = %v, want %v", stored.Spec.Template.Labels, want)
+	}
+
+	r = newTestRun(t, testDeployment())
+	if err := r.run("web", "-n", "team", "--pod-template", "--label=tier=frontend", "--label=version-"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	stored, err = r.clientset.AppsV1().Deployments("team").Get(context.TODO(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"app": "web", "tier": "frontend"}; !reflect.DeepEqual(stored.Spec.Template.Labels, want) {
+		t.Errorf("template labels = %v, want %v", stored.Spec.Template.Labels, want)
+	}
+	for _, want := range []string{
+		`Warning: label "version" not found on deployment "web"`,
+		`Warning: label "version" not found on the pod template of deployment "web"`,
+	} {
+		if !strings.Contains(r.errOut.String(), want) {
+			t.Errorf("errOut misses %q:\n%s", want, r.errOut.String())
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+
+	"common/protection"
+)
+
+//Function to stop destructive edits in the protectedNamespaces of the config file
+//Reports true when the edit may go ahead, a dry run changes nothing and is not asked about
+func (o *EditDeployOptions) checkProtection() (bool, error) {
+	if !o.protection.Protected(o.namespace) {
+		return true, nil
+	}
+	operations := o.protectedOperations()
+	if len(operations) == 0 {
+		return true, nil
+	}
+
+	fmt.Fprintf(o.Out, "Namespace %s is protected, deployment %s plans:\n", o.namespace, o.deploymentName)
+	for _, operation := range operations {
+		fmt.Fprintf(o.Out, "  %s\n", operation)
+	}
+
+	target := fmt.Sprintf("protected namespace %q", o.namespace)
+	if o.dryRun != dryRunNone && o.overrideProtection {
+		return true, nil
+	}
+	proceed, err := protection.Confirm(o.answers, o.Out, target, o.overrideProtection, operations)
+	if err != nil {
+		return false, err
+	}
+	if !proceed {
+		fmt.Fprintln(o.Out, "Edit cancelled..")
+	}
+	return proceed, nil
+}
+
+//Destructive parts of the edit: scaling down or to zero and pod template changes that restart every pod
+func (o *EditDeployOptions) protectedOperations() []string {
+	var operations []string
+
+	current := replicasOf(o.live)
+	switch {
+	case o.newReplicas == 0 && current > 0:
+		operations = append(operations, fmt.Sprintf("scale-to-zero %d -> 0", current))
+	case o.newReplicas < current:
+		operations = append(operations, fmt.Sprintf("scale-down %d -> %d", current, o.newReplicas))
+	}
+
+	proposed := o.live.DeepCopy()
+	o.applyChanges(proposed)
+	if !equality.Semantic.DeepEqual(o.live.Spec.Template, proposed.Spec.Template) {
+		operations = append(operations, "restart of all pods (pod template change)")
+	}
+	return operations
+}
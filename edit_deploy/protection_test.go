@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProtectedOperations(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"scale-down", []string{"--replicas=1"}, "scale-down 3 -> 1"},
+		{"scale-to-zero", []string{"--replicas=0"}, "scale-to-zero 3 -> 0"},
+		{"pod template", []string{"--grace-period=60"}, "restart of all pods (pod template change)"},
+		{"pod template labels", []string{"--label=tier=frontend", "--pod-template"}, "restart of all pods (pod template change)"},
+		{"scale-down and pod template", []string{"--replicas=2", "--grace-period=60"}, "scale-down 3 -> 2 and restart of all pods (pod template change)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//Refused without --override-protection
+			r := newTestRun(t, testDeployment())
+			protectNamespaces(t, "kube-system", "team")
+			err := r.run(append([]string{"web", "-n", "team"}, tt.args...)...)
+			want := `protected namespace "team": ` + tt.want + " needs --override-protection"
+			if err == nil || err.Error() != want {
+				t.Fatalf("run = %v, want %q", err, want)
+			}
+			if updates := countUpdates(r); updates != 0 {
+				t.Errorf("got %d updates, want none", updates)
+			}
+
+			//Asked with --override-protection, declining changes nothing
+			r = newTestRun(t, testDeployment())
+			protectNamespaces(t, "team")
+			r.in.WriteString("n\n")
+			if err := r.run(append([]string{"web", "-n", "team", "--override-protection"}, tt.args...)...); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if prompt := "Run " + tt.want + ` in protected namespace "team"? [y/N]: `; !strings.Contains(r.out.String(), prompt) {
+				t.Errorf("out misses %q:\n%s", prompt, r.out.String())
+			}
+			if !strings.Contains(r.out.String(), "Edit cancelled..") || countUpdates(r) != 0 {
+				t.Errorf("declined edit was applied:\n%s", r.out.String())
+			}
+
+			//Accepting applies it
+			r = newTestRun(t, testDeployment())
+			protectNamespaces(t, "team")
+			r.in.WriteString("y\n")
+			if err := r.run(append([]string{"web", "-n", "team", "--override-protection"}, tt.args...)...); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if updates := countUpdates(r); updates != 1 {
+				t.Errorf("got %d updates after confirming, want 1", updates)
+			}
+		})
+	}
+}
+
+func TestProtectionAllowsSafeEdits(t *testing.T) {
+	tests := []struct {
+		name      string
+		protected []string
+		args      []string
+	}{
+		{"scale-up", []string{"team"}, []string{"--replicas=5"}},
+		{"revision history", []string{"team"}, []string{"--rhl=3"}},
+		{"deployment label only", []string{"team"}, []string{"--label=tier=frontend"}},
+		{"other namespace", []string{"kube-system"}, []string{"--replicas=0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRun(t, testDeployment())
+			protectNamespaces(t, tt.protected...)
+			if err := r.run(append([]string{"web", "-n", "team"}, tt.args...)...); err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if strings.Contains(r.out.String(), "is protected") {
+				t.Errorf("safe edit was treated as protected:\n%s", r.out.String())
+			}
+		})
+	}
+}
+
+//A dry run with --override-protection lists the operations but does not ask
+func TestProtectionDryRun(t *testing.T) {
+	r := newTestRun(t, testDeployment())
+	protectNamespaces(t, "team")
+	if err := r.run("web", "-n", "team", "--replicas=0", "--override-protection", "--dry-run"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	want := "Namespace team is protected, deployment web plans:\n  scale-to-zero 3 -> 0\nUpdated Deployment.. (dry run) replicas=0, revisionHistoryLimit=10\n"
+	if got := r.out.String(); got != want {
+		t.Errorf("out = %q, want %q", got, want)
+	}
+}
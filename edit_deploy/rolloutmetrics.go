@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//Annotation the deployment controller sets on a deployment and its ReplicaSets
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+//Numbers collected while waiting for the rollout, printed with the rollout summary
+type rolloutMetrics struct {
+	//Time the update was accepted by the API server
+	accepted time.Time
+	duration time.Duration
+	//Pods of the new ReplicaSet created after the update
+	recreated int
+	//Restart count per pod of the new ReplicaSet when it was first seen, 0 for pods created after the update
+	baselines map[string]int32
+	//Highest number of restarts since the baseline seen per pod of the new ReplicaSet
+	restarts map[string]int32
+}
+
+func newRolloutMetrics(accepted time.Time) *rolloutMetrics {
+	return &rolloutMetrics{accepted: accepted, baselines: map[string]int32{}, restarts: map[string]int32{}}
+}
+
+//Function to record the pods of the new ReplicaSet, restarts are only visible while the pods exist so it runs on every poll
+//Failing to collect the metrics never fails the wait
+func (o *EditDeployOptions) observeRollout(metrics *rolloutMetrics, deployment *appsv1.Deployment) {
+	replicaSet, err := o.newReplicaSet(deployment)
+	if err != nil {
+		o.log.V(1).Infof("cannot collect rollout metrics: %v", err)
+		return
+	}
+	if replicaSet == nil {
+		return
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(replicaSet.Spec.Selector)
+	if err != nil {
+		o.log.V(1).Infof("cannot collect rollout metrics: %v", err)
+		return
+	}
+	start := time.Now()
+	pods, err := o.clientset.CoreV1().Pods(o.namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	o.log.V(2).Infof("LIST pods of replicaset %s/%s (%v)", o.namespace, replicaSet.Name, time.Since(start))
+	if err != nil {
+		o.log.V(1).Infof("cannot collect rollout metrics: %v", err)
+		return
+	}
+
+	metrics.record(pods.Items)
+}
+
+//Function to count the recreated pods and their restarts during the rollout
+//A pod kept from before the update, e.g. when only the replicas changed, may have restarted long ago
+//so only restarts after it was first seen count
+func (m *rolloutMetrics) record(pods []corev1.Pod) {
+	//Creation timestamps only have second precision
+	since := metav1.NewTime(m.accepted.Truncate(time.Second))
+	recreated := 0
+	for _, pod := range pods {
+		created := !pod.CreationTimestamp.Before(&since)
+		if created {
+			recreated++
+		}
+		baseline, seen := m.baselines[pod.Name]
+		if !seen {
+			if !created {
+				baseline = podRestarts(pod)
+			}
+			m.baselines[pod.Name] = baseline
+		}
+		if restarts := podRestarts(pod) - baseline; restarts > m.restarts[pod.Name] {
+			m.restarts[pod.Name] = restarts
+		}
+	}
+	if recreated > m.recreated {
+		m.recreated = recreated
+	}
+}
+
+//The ReplicaSet of the current revision of the deployment, nil until the controller created it
+func (o *EditDeployOptions) newReplicaSet(deployment *appsv1.Deployment) (*appsv1.ReplicaSet, error) {
+	revision, ok := deployment.Annotations[revisionAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	replicaSets, err := o.clientset.AppsV1().ReplicaSets(o.namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	o.log.V(2).Infof("LIST replicasets of deployment %s/%s (%v)", o.namespace, deployment.Name, time.Since(start))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range replicaSets.Items {
+		replicaSet := &replicaSets.Items[i]
+		if metav1.IsControlledBy(replicaSet, deployment) && replicaSet.Annotations[revisionAnnotation] == revision {
+			return replicaSet, nil
+		}
+	}
+	return nil, nil
+}
+
+func podRestarts(pod corev1.Pod) int32 {
+	var restarts int32
+	for _, status := range pod.Status.ContainerStatuses {
+		restarts += status.RestartCount
+	}
+	return restarts
+}
+
+//Pods that restarted more than once, sorted by name
+func (m *rolloutMetrics) flappingPods() []string {
+	var pods []string
+	for name, restarts := range m.restarts {
+		if restarts > 1 {
+			pods = append(pods, fmt.Sprintf("%s (%d restarts)", name, restarts))
+		}
+	}
+	sort.Strings(pods)
+	return pods
+}
+
+func (m *rolloutMetrics) String() string {
+	summary := fmt.Sprintf("took %v, %d pods recreated", m.duration.Round(time.Second), m.recreated)
+	if flapping := m.flappingPods(); len(flapping) > 0 {
+		summary += fmt.Sprintf(", restarted more than once: %s", strings.Join(flapping, ", "))
+	} else {
+		summary += ", no pod restarted more than once"
+	}
+	return summary
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testPod(name string, created time.Time, restarts int32) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created)},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "web", RestartCount: restarts}},
+		},
+	}
+}
+
+func TestRolloutMetricsRestartDeltas(t *testing.T) {
+	accepted := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	before := accepted.Add(-24 * time.Hour)
+	after := accepted.Add(5 * time.Second)
+	metrics := newRolloutMetrics(accepted)
+
+	//old-a restarted 5 times the day before and twice during the rollout
+	//old-b restarted 9 times the day before and not since
+	//new-a was created by the rollout and restarted 3 times before it was first seen
+	metrics.record([]corev1.Pod{
+		testPod("old-a", before, 5),
+		testPod("old-b", before, 9),
+		testPod("new-a", after, 3),
+	})
+	metrics.record([]corev1.Pod{
+		testPod("old-a", before, 7),
+		testPod("old-b", before, 9),
+		testPod("new-a", after, 3),
+		testPod("new-b", after, 0),
+	})
+
+	want := map[string]int32{"old-a": 2, "old-b": 0, "new-a": 3, "new-b": 0}
+	for name, restarts := range want {
+		if got := metrics.restarts[name]; got != restarts {
+			t.Errorf("restarts of %s = %d, want %d", name, got, restarts)
+		}
+	}
+	if metrics.recreated != 2 {
+		t.Errorf("recreated = %d, want 2", metrics.recreated)
+	}
+
+	metrics.duration = 90 * time.Second
+	if got, want := metrics.String(), "took 1m30s, 2 pods recreated, restarted more than once: new-a (3 restarts), old-a (2 restarts)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestRolloutMetricsNoRestarts(t *testing.T) {
+	accepted := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	metrics := newRolloutMetrics(accepted)
+	metrics.record([]corev1.Pod{testPod("old", accepted.Add(-time.Hour), 12)})
+	metrics.record([]corev1.Pod{testPod("old", accepted.Add(-time.Hour), 12)})
+
+	if got, want := metrics.String(), "took 0s, 0 pods recreated, no pod restarted more than once"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
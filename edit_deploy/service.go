@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+//Function to find the deployment whose pods are selected by --service
+//The selector of the Service must be a subset of the pod template labels of exactly one deployment
+func (o *EditDeployOptions) resolveService() (string, error) {
+	start := time.Now()
+	service, err := o.clientset.CoreV1().Services(o.namespace).Get(context.TODO(), o.service, metav1.GetOptions{})
+	o.log.V(2).Infof("GET service %s/%s (%v)", o.namespace, o.service, time.Since(start))
+	if err != nil {
+		return "", fmt.Errorf("failed to get service: %w", err)
+	}
+	if len(service.Spec.Selector) == 0 {
+		return "", fmt.Errorf("service %s/%s has no selector, it is not backed by a deployment", o.namespace, o.service)
+	}
+
+	start = time.Now()
+	deployments, err := o.deploymentsClient.List(context.TODO(), metav1.ListOptions{})
+	o.log.V(2).Infof("LIST deployments %s (%v)", o.namespace, time.Since(start))
+	if err != nil {
+		return "", fmt.Errorf("failed to list deployments: %w", err)
+	}
+
+	selector := labels.SelectorFromSet(service.Spec.Selector)
+	var candidates []string
+	for _, deployment := range deployments.Items {
+		if selector.Matches(labels.Set(deployment.Spec.Template.Labels)) {
+			candidates = append(candidates, deployment.Name)
+		}
+	}
+	sort.Strings(candidates)
+
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("no deployment in namespace %q has pods selected by service %q (selector %s)", o.namespace, o.service, selector)
+	case 1:
+		o.log.V(1).Infof("service %s/%s is backed by deployment %s", o.namespace, o.service, candidates[0])
+		return candidates[0], nil
+	default:
+		return "", fmt.Errorf("service %q (selector %s) matches several deployments, pass one of them by name: %s", o.service, selector, strings.Join(candidates, ", "))
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//Service in namespace team with the given selector
+func testService(name string, selector map[string]string) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team"},
+		Spec:       corev1.ServiceSpec{Selector: selector},
+	}
+}
+
+//Deployment in namespace team whose pods carry the given labels
+func labeledDeployment(name string, labels map[string]string) *appsv1.Deployment {
+	deployment := testDeployment()
+	deployment.Name = name
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+	deployment.Spec.Template.Labels = labels
+	return deployment
+}
+
+func TestResolveService(t *testing.T) {
+	r := newTestRun(t,
+		testService("frontend", map[string]string{"app": "web"}),
+		labeledDeployment("web", map[string]string{"app": "web", "track": "stable"}),
+		labeledDeployment("api", map[string]string{"app": "api"}),
+	)
+	if err := r.run("-n", "team", "--service=frontend", "--replicas=5"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	stored, err := r.clientset.AppsV1().Deployments("team").Get(context.TODO(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replicasOf(stored) != 5 {
+		t.Errorf("replicas of web = %d, want 5", replicasOf(stored))
+	}
+	if updates := countUpdates(r); updates != 1 {
+		t.Errorf("got %d updates, want 1", updates)
+	}
+}
+
+func TestResolveServiceErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		service *corev1.Service
+		want    string
+	}{
+		{
+			name:    "no deployment",
+			service: testService("frontend", map[string]string{"app": "shop"}),
+			want:    `no deployment in namespace "team" has pods selected by service "frontend" (selector app=shop)`,
+		},
+		{
+			name:    "several deployments",
+			service: testService("frontend", map[string]string{"tier": "frontend"}),
+			want:    `service "frontend" (selector tier=frontend) matches several deployments, pass one of them by name: api, web`,
+		},
+		{
+			name:    "no selector",
+			service: testService("frontend", nil),
+			want:    "service team/frontend has no selector, it is not backed by a deployment",
+		},
+		{
+			name:    "missing service",
+			service: testService("backend", map[string]string{"app": "web"}),
+			want:    `failed to get service: services "frontend" not found`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRun(t,
+				tt.service,
+				labeledDeployment("web", map[string]string{"app": "web", "tier": "frontend"}),
+				labeledDeployment("api", map[string]string{"app": "api", "tier": "frontend"}),
+			)
+			err := r.run("-n", "team", "--service=frontend", "--replicas=5")
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("run = %v, want %q", err, tt.want)
+			}
+			if updates := countUpdates(r); updates != 0 {
+				t.Errorf("got %d updates, want none", updates)
+			}
+		})
+	}
+}
+
+//A name argument is not silently replaced by the deployment behind --service
+func TestResolveServiceWithName(t *testing.T) {
+	r := newTestRun(t, testService("frontend", map[string]string{"app": "web"}), testDeployment())
+	err := r.run("web", "-n", "team", "--service=frontend", "--replicas=5")
+	if err == nil || !strings.Contains(err.Error(), "--service replaces the deployment name argument") {
+		t.Fatalf("run = %v, want the name and --service to be rejected together", err)
+	}
+}
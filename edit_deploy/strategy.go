@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+//Annotation kubectl uses to record why a rollout happened, shown by kubectl rollout history
+const changeCauseAnnotation = "kubernetes.io/change-cause"
+
+//Defaults the API server uses for a RollingUpdate strategy
+var (
+	defaultMaxSurge       = intstr.FromString("25%")
+	defaultMaxUnavailable = intstr.FromString("25%")
+)
+
+//Function to validate --strategy, --max-surge and --max-unavailable
+func (o *EditDeployOptions) validateStrategy() error {
+	switch appsv1.DeploymentStrategyType(o.strategy) {
+	case "", appsv1.RollingUpdateDeploymentStrategyType, appsv1.RecreateDeploymentStrategyType:
+	default:
+		return fmt.Errorf("invalid --strategy %q, must be %q or %q", o.strategy, appsv1.RollingUpdateDeploymentStrategyType, appsv1.RecreateDeploymentStrategyType)
+	}
+
+	if len(o.maxSurge) == 0 && len(o.maxUnavailable) == 0 {
+		return nil
+	}
+
+	if appsv1.DeploymentStrategyType(o.strategy) == appsv1.RecreateDeploymentStrategyType {
+		return fmt.Errorf("--strategy=Recreate cannot be combined with --max-surge or --max-unavailable, they only apply to RollingUpdate")
+	}
+	if len(o.strategy) == 0 && o.live != nil && o.live.Spec.Strategy.Type == appsv1.RecreateDeploymentStrategyType {
+		return fmt.Errorf("deployment %q uses the Recreate strategy, add --strategy=RollingUpdate to set --max-surge or --max-unavailable", o.deploymentName)
+	}
+
+	//Surging above 100% is allowed, more than all pods cannot be unavailable
+	if err := validateIntOrPercent("max-surge", o.maxSurge, false); err != nil {
+		return err
+	}
+	return validateIntOrPercent("max-unavailable", o.maxUnavailable, true)
+}
+
+//Accept a non-negative number or percentage such as 2 or 25%, with upTo100 percentages above 100% are rejected
+func validateIntOrPercent(flagName, value string, upTo100 bool) error {
+	if len(value) == 0 {
+		return nil
+	}
+	number, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+	if err != nil || number < 0 {
+		return fmt.Errorf("invalid --%s %q, must be a non-negative number or percentage", flagName, value)
+	}
+	if upTo100 && strings.HasSuffix(value, "%") && number > 100 {
+		return fmt.Errorf("invalid --%s %q, must not be more than 100%%", flagName, value)
+	}
+	return nil
+}
+
+//Function to set the requested update strategy on the deployment
+func (o *EditDeployOptions) applyStrategy(deployment *appsv1.Deployment) {
+	strategy := &deployment.Spec.Strategy
+
+	switch appsv1.DeploymentStrategyType(o.strategy) {
+	case appsv1.RecreateDeploymentStrategyType:
+		strategy.Type = appsv1.RecreateDeploymentStrategyType
+		//The API rejects rollingUpdate parameters on a Recreate strategy
+		strategy.RollingUpdate = nil
+	case appsv1.RollingUpdateDeploymentStrategyType:
+		strategy.Type = appsv1.RollingUpdateDeploymentStrategyType
+	}
+
+	//Defaults are only filled in when the strategy flags are used, other edits leave the strategy as it is
+	rollingUpdate := appsv1.DeploymentStrategyType(o.strategy) == appsv1.RollingUpdateDeploymentStrategyType || len(o.maxSurge) > 0 || len(o.maxUnavailable) > 0
+	if rollingUpdate && strategy.RollingUpdate == nil {
+		maxSurge, maxUnavailable := defaultMaxSurge, defaultMaxUnavailable
+		strategy.RollingUpdate = &appsv1.RollingUpdateDeployment{MaxSurge: &maxSurge, MaxUnavailable: &maxUnavailable}
+	}
+
+	if len(o.maxSurge) > 0 {
+		maxSurge := intstr.Parse(o.maxSurge)
+		strategy.RollingUpdate.MaxSurge = &maxSurge
+	}
+	if len(o.maxUnavailable) > 0 {
+		maxUnavailable := intstr.Parse(o.maxUnavailable)
+		strategy.RollingUpdate.MaxUnavailable = &maxUnavailable
+	}
+
+	if cause := o.strategyChangeCause(); len(cause) > 0 {
+		if deployment.Annotations == nil {
+			deployment.Annotations = map[string]string{}
+		}
+		deployment.Annotations[changeCauseAnnotation] = cause
+	}
+}
+
+//The command line that changed the strategy, empty when the strategy is not touched
+func (o *EditDeployOptions) strategyChangeCause() string {
+	var flags []string
+	if len(o.strategy) > 0 {
+		flags = append(flags, "--strategy="+o.strategy)
+	}
+	if len(o.maxSurge) > 0 {
+		flags = append(flags, "--max-surge="+o.maxSurge)
+	}
+	if len(o.maxUnavailable) > 0 {
+		flags = append(flags, "--max-unavailable="+o.maxUnavailable)
+	}
+	if len(flags) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("kubectl edit-deploy %s %s", o.deploymentName, strings.Join(flags, " "))
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestValidateStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		recreate bool
+		args     []string
+		want     string
+	}{
+		{"unknown strategy", false, []string{"--strategy=BlueGreen"}, `invalid --strategy "BlueGreen", must be "RollingUpdate" or "Recreate"`},
+		{"Recreate with max-surge", false, []string{"--strategy=Recreate", "--max-surge=1"}, "--strategy=Recreate cannot be combined with --max-surge or --max-unavailable, they only apply to RollingUpdate"},
+		{"Recreate with max-unavailable", false, []string{"--strategy=Recreate", "--max-unavailable=1"}, "--strategy=Recreate cannot be combined with --max-surge or --max-unavailable, they only apply to RollingUpdate"},
+		{"live Recreate with max-surge", true, []string{"--max-surge=1"}, `deployment "web" uses the Recreate strategy, add --strategy=RollingUpdate to set --max-surge or --max-unavailable`},
+		{"negative max-surge", false, []string{"--max-surge=-1"}, `invalid --max-surge "-1", must be a non-negative number or percentage`},
+		{"max-surge not a number", false, []string{"--max-surge=many"}, `invalid --max-surge "many", must be a non-negative number or percentage`},
+		{"max-unavailable over 100%", false, []string{"--max-unavailable=150%"}, `invalid --max-unavailable "150%", must not be more than 100%`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := testDeployment()
+			if tt.recreate {
+				deployment.Spec.Strategy = appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+			}
+			r := newTestRun(t, deployment)
+			err := r.run(append([]string{"web", "-n", "team"}, tt.args...)...)
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("run = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateStrategyAccepts(t *testing.T) {
+	for _, args := range [][]string{
+		{"--max-surge=150%"},
+		{"--max-unavailable=100%"},
+		{"--max-surge=3", "--max-unavailable=0"},
+		{"--strategy=RollingUpdate", "--max-surge=0%"},
+		{"--strategy=Recreate"},
+	} {
+		r := newTestRun(t, testDeployment())
+		if err := r.complete(append([]string{"web", "-n", "team"}, args...)...); err != nil {
+			t.Fatalf("complete(%v): %v", args, err)
+		}
+		if err := r.o.validateStrategy(); err != nil {
+			t.Errorf("validateStrategy(%v) = %v, want nil", args, err)
+		}
+	}
+}
+
+func TestApplyStrategy(t *testing.T) {
+	surge := func(s string) *intstr.IntOrString { v := intstr.Parse(s); return &v }
+	tests := []struct {
+		name      string
+		recreate  bool
+		args      []string
+		wantType  appsv1.DeploymentStrategyType
+		wantParam *appsv1.RollingUpdateDeployment
+		wantCause string
+	}{
+		{
+			name:     "untouched",
+			args:     []string{"--replicas=5"},
+			wantType: appsv1.RollingUpdateDeploymentStrategyType,
+		},
+		{
+			name:      "Recreate",
+			args:      []string{"--strategy=Recreate"},
+			wantType:  appsv1.RecreateDeploymentStrategyType,
+			wantCause: "kubectl edit-deploy web --strategy=Recreate",
+		},
+		{
+			name:      "RollingUpdate defaults",
+			recreate:  true,
+			args:      []string{"--strategy=RollingUpdate"},
+			wantType:  appsv1.RollingUpdateDeploymentStrategyType,
+			wantParam: &appsv1.RollingUpdateDeployment{MaxSurge: surge("25%"), MaxUnavailable: surge("25%")},
+			wantCause: "kubectl edit-deploy web --strategy=RollingUpdate",
+		},
+		{
+			name:      "max-surge keeps the default max-unavailable",
+			args:      []string{"--max-surge=2"},
+			wantType:  appsv1.RollingUpdateDeploymentStrategyType,
+			wantParam: &appsv1.RollingUpdateDeployment{MaxSurge: surge("2"), MaxUnavailable: surge("25%")},
+			wantCause: "kubectl edit-deploy web --max-surge=2",
+		},
+		{
+			name:      "both parameters",
+			recreate:  true,
+			args:      []string{"--strategy=RollingUpdate", "--max-surge=50%", "--max-unavailable=0"},
+			wantType:  appsv1.RollingUpdateDeploymentStrategyType,
+			wantParam: &appsv1.RollingUpdateDeployment{MaxSurge: surge("50%"), MaxUnavailable: surge("0")},
+			wantCause: "kubectl edit-deploy web --strategy=RollingUpdate --max-surge=50% --max-unavailable=0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := testDeployment()
+			if tt.recreate {
+				deployment.Spec.Strategy = appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+			}
+			r := newTestRun(t, deployment)
+			if err := r.complete(append([]string{"web", "-n", "team"}, tt.args...)...); err != nil {
+				t.Fatalf("complete: %v", err)
+			}
+
+			edited := deployment.DeepCopy()
+			r.o.applyStrategy(edited)
+			strategy := edited.Spec.Strategy
+			if strategy.Type != tt.wantType {
+				t.Errorf("type = %q, want %q", strategy.Type, tt.wantType)
+			}
+			if (strategy.RollingUpdate == nil) != (tt.wantParam == nil) {
+				t.Fatalf("rollingUpdate = %+v, want %+v", strategy.RollingUpdate, tt.wantParam)
+			}
+			if tt.wantParam != nil && (*strategy.RollingUpdate.MaxSurge != *tt.wantParam.MaxSurge || *strategy.RollingUpdate.MaxUnavailable != *tt.wantParam.MaxUnavailable) {
+				t.Errorf("rollingUpdate = %v/%v, want %v/%v", strategy.RollingUpdate.MaxSurge, strategy.RollingUpdate.MaxUnavailable, tt.wantParam.MaxSurge, tt.wantParam.MaxUnavailable)
+			}
+			if cause := edited.Annotations[changeCauseAnnotation]; cause != tt.wantCause {
+				t.Errorf("change-cause = %q, want %q", cause, tt.wantCause)
+			}
+		})
+	}
+}
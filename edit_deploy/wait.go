@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+//How often the deployment status is polled while waiting
+const rolloutPollInterval = 2 * time.Second
+
+//Function to block until the new replicas of the deployment are available
+//accepted is the time the update was accepted, the rollout duration is measured from it
+func (o *EditDeployOptions) waitForRollout(accepted time.Time) error {
+	fmt.Fprintf(o.Out, "Waiting for rollout of deployment %q to finish..\n", o.deploymentName)
+	metrics := newRolloutMetrics(accepted)
+
+	pollErr := wait.PollImmediate(rolloutPollInterval, o.timeout, func() (bool, error) {
+		start := time.Now()
+		result, getErr := o.deploymentsClient.Get(context.TODO(), o.deploymentName, metav1.GetOptions{})
+		o.log.V(2).Infof("GET deployment %s/%s (%v)", o.namespace, o.deploymentName, time.Since(start))
+		if getErr != nil {
+			return false, getErr
+		}
+		o.observeRollout(metrics, result)
+		return rolloutComplete(result)
+	})
+
+	if pollErr == wait.ErrWaitTimeout {
+		return fmt.Errorf("timed out after %v waiting for rollout of deployment %q", o.timeout, o.deploymentName)
+	}
+	if pollErr != nil {
+		return fmt.Errorf("waiting for rollout failed: %w", pollErr)
+	}
+
+	metrics.duration = time.Since(accepted)
+	fmt.Fprintf(o.Out, "Rollout complete.. %s\n", metrics)
+	return nil
+}
+
+//Same checks as kubectl rollout status
+func rolloutComplete(deployment *appsv1.Deployment) (bool, error) {
+	if deployment.Generation > deployment.Status.ObservedGeneration {
+		return false, nil
+	}
+
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Status == corev1.ConditionFalse && condition.Reason == "ProgressDeadlineExceeded" {
+			return false, fmt.Errorf("deployment %q exceeded its progress deadline", deployment.Name)
+		}
+	}
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	status := deployment.Status
+	return status.UpdatedReplicas == replicas && status.Replicas == replicas && status.AvailableReplicas == replicas, nil
+}
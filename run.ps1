@@ -1,11 +1,11 @@
 cd .\edit_cr
 go mod tidy
-go build .\kubectl-edit_cr.go
+go build -o kubectl-edit_cr.exe .
 Copy-Item "./kubectl-edit_cr.exe" -Destination "../../FalconCoreServices.Kubernetes/bin"
 
 cd ..\edit_deploy
 go mod tidy
-go build .\kubectl-edit_deploy.go
+go build -o kubectl-edit_deploy.exe .
 Copy-Item "./kubectl-edit_deploy.exe" -Destination "../../FalconCoreServices.Kubernetes/bin"
 cd ..
 